@@ -1,10 +1,13 @@
 // main.go
 package main
 
-import "github.com/Sp92535/GoGate-RateLimiter/internal/proxy"
+import (
+	"os"
 
-func main() {
-	// call to start the registered proxies
-	proxy.Run()
+	"github.com/Sp92535/GoGate-RateLimiter/internal/cli"
+)
 
+func main() {
+	// running the subcommand given on the command line
+	os.Exit(cli.Run(os.Args[1:]))
 }
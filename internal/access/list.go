@@ -0,0 +1,178 @@
+// list.go
+package access
+
+import (
+	"context"
+	"crypto/sha256"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// entries matched against a request
+type entries struct {
+	// single addresses and cidr ranges
+	prefixes []netip.Prefix
+
+	// sha256 hex digests of api keys
+	apiKeys map[string]bool
+}
+
+// function to add an entry, ips and cidrs are detected, anything else is an api key digest
+func (e *entries) add(entry string) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return
+	}
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		e.prefixes = append(e.prefixes, prefix.Masked())
+		return
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		e.prefixes = append(e.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		return
+	}
+	e.apiKeys[strings.ToLower(entry)] = true
+}
+
+// function to check if ip or digest of api key is listed
+func (e *entries) contains(ip netip.Addr, apiKey string) bool {
+	if apiKey != "" && e.apiKeys[apiKey] {
+		return true
+	}
+	if !ip.IsValid() {
+		return false
+	}
+	for _, prefix := range e.prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// list of callers matched by ip, api key or header
+type List struct {
+	// entries from config
+	static *entries
+
+	// required header values, "*" only requires presence
+	headers map[string]string
+
+	// redis set changeable at runtime, synced periodically
+	set     string
+	dynamic *entries
+	mu      sync.RWMutex
+
+	// context for closure
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// constructor to initialize list
+func NewList(cfg *utils.AccessList, rdb *redis.Client) *List {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &List{
+		static:  &entries{apiKeys: make(map[string]bool)},
+		headers: cfg.Headers,
+		set:     cfg.RedisSet,
+		dynamic: &entries{apiKeys: make(map[string]bool)},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, entry := range cfg.IPs {
+		if _, err := netip.ParsePrefix(entry); err != nil {
+			if _, err := netip.ParseAddr(entry); err != nil {
+				log.Fatalf("invalid ip or cidr %s", entry)
+			}
+		}
+		l.static.add(entry)
+	}
+	for _, key := range cfg.APIKeys {
+		if len(key) != sha256.Size*2 {
+			log.Fatalf("api key %s of access list is not a sha256 hex digest", key)
+		}
+		l.static.apiKeys[strings.ToLower(key)] = true
+	}
+
+	// starting the syncing of redis set as a go routine
+	if l.set != "" {
+		l.sync(rdb)
+		go l.watch(rdb, cfg.RefreshInterval)
+	}
+
+	return l
+}
+
+// function to check if request matches the list, api key being its sha256 hex digest
+func (l *List) Match(r *http.Request, ip string, apiKey string) bool {
+	addr, _ := netip.ParseAddr(ip)
+	addr = addr.Unmap()
+
+	if l.static.contains(addr, apiKey) {
+		return true
+	}
+
+	l.mu.RLock()
+	listed := l.dynamic.contains(addr, apiKey)
+	l.mu.RUnlock()
+	if listed {
+		return true
+	}
+
+	for name, value := range l.headers {
+		got := r.Header.Get(name)
+		if got != "" && (value == "*" || got == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// function to reload entries of redis set
+func (l *List) sync(rdb *redis.Client) {
+	members, err := rdb.SMembers(l.ctx, l.set).Result()
+	if err != nil {
+		log.Printf("Error syncing %s: %v", l.set, err)
+		return
+	}
+	dynamic := &entries{apiKeys: make(map[string]bool)}
+	for _, member := range members {
+		dynamic.add(member)
+	}
+
+	l.mu.Lock()
+	l.dynamic = dynamic
+	l.mu.Unlock()
+}
+
+// function to sync redis set periodically
+func (l *List) watch(rdb *redis.Client, interval time.Duration) {
+
+	// initialize ticker to tick every interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+			l.sync(rdb)
+
+		// returning from function if context is cancelled
+		case <-l.ctx.Done():
+			return
+		}
+	}
+}
+
+// function to stop syncing
+func (l *List) Stop() {
+	l.cancel()
+}
@@ -0,0 +1,92 @@
+// list_test.go
+package access
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/auth"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestListMatch(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mr.SAdd("gogate:allow", "172.16.0.0/12", "198.51.100.7", strings.ToUpper(auth.HashAPIKey("dynamic-key")), "not an entry")
+
+	l := NewList(&utils.AccessList{
+		IPs:             []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32", "10.1.2.3/16"},
+		APIKeys:         []string{auth.HashAPIKey("static-key")},
+		Headers:         map[string]string{"X-Internal": "*", "X-Team": "ops"},
+		RedisSet:        "gogate:allow",
+		RefreshInterval: time.Hour,
+	}, rdb)
+	t.Cleanup(l.Stop)
+
+	tests := []struct {
+		name    string
+		ip      string
+		apiKey  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "in cidr", ip: "10.20.30.40", want: true},
+		{name: "cidr upper bound", ip: "10.255.255.255", want: true},
+		{name: "outside cidr", ip: "11.0.0.1"},
+		{name: "single ip", ip: "192.168.1.10", want: true},
+		{name: "next to single ip", ip: "192.168.1.11"},
+		{name: "v6 cidr", ip: "2001:db8:1::1", want: true},
+		{name: "outside v6 cidr", ip: "2001:db9::1"},
+		{name: "mapped v4", ip: "::ffff:10.0.0.1", want: true},
+		{name: "invalid ip", ip: "unknown"},
+		{name: "static api key", ip: "203.0.113.1", apiKey: auth.HashAPIKey("static-key"), want: true},
+		{name: "unknown api key", ip: "203.0.113.1", apiKey: auth.HashAPIKey("other-key")},
+		{name: "redis cidr", ip: "172.31.255.1", want: true},
+		{name: "redis ip", ip: "198.51.100.7", want: true},
+		{name: "redis api key in upper case", apiKey: auth.HashAPIKey("dynamic-key"), want: true},
+		{name: "any header value", ip: "203.0.113.1", headers: map[string]string{"X-Internal": "yes"}, want: true},
+		{name: "header value", ip: "203.0.113.1", headers: map[string]string{"X-Team": "ops"}, want: true},
+		{name: "wrong header value", ip: "203.0.113.1", headers: map[string]string{"X-Team": "dev"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if got := l.Match(r, tt.ip, tt.apiKey); got != tt.want {
+				t.Errorf("got match %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSync(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mr.SAdd("gogate:deny", "203.0.113.0/24")
+
+	l := NewList(&utils.AccessList{RedisSet: "gogate:deny", RefreshInterval: time.Hour}, rdb)
+	t.Cleanup(l.Stop)
+	r := httptest.NewRequest("GET", "/", nil)
+	if !l.Match(r, "203.0.113.9", "") {
+		t.Fatal("listed ip not matched")
+	}
+
+	// entries changed at runtime are taken on the next sync
+	mr.SRem("gogate:deny", "203.0.113.0/24")
+	mr.SAdd("gogate:deny", "198.51.100.1")
+	l.sync(rdb)
+	if l.Match(r, "203.0.113.9", "") {
+		t.Error("removed range still matched")
+	}
+	if !l.Match(r, "198.51.100.1", "") {
+		t.Error("added ip not matched")
+	}
+}
@@ -0,0 +1,81 @@
+// policy.go
+package access
+
+import (
+	"net/http"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/auth"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// decision of a policy for a request
+type Decision int
+
+const (
+	// request goes through the limiters
+	None Decision = iota
+	// request bypasses the limiters
+	Allow
+	// request is rejected
+	Deny
+)
+
+// allow and deny lists of a scope
+type Policy struct {
+	allow *List
+	deny  *List
+
+	// header carrying the api key
+	apiKeyHeader string
+}
+
+// constructor to initialize policy, nil if nothing is configured
+func NewPolicy(cfg *utils.Access, rdb *redis.Client) *Policy {
+	if cfg == nil {
+		return nil
+	}
+	p := &Policy{apiKeyHeader: cfg.APIKeyHeader}
+	if cfg.Allow != nil {
+		p.allow = NewList(cfg.Allow, rdb)
+	}
+	if cfg.Deny != nil {
+		p.deny = NewList(cfg.Deny, rdb)
+	}
+	return p
+}
+
+// function to decide on a request across policies, deny wins over allow
+func Decide(r *http.Request, ip string, policies ...*Policy) Decision {
+	decision := None
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		// keys are listed by digest as for api key authentication
+		apiKey := r.Header.Get(p.apiKeyHeader)
+		if apiKey != "" {
+			apiKey = auth.HashAPIKey(apiKey)
+		}
+		if p.deny != nil && p.deny.Match(r, ip, apiKey) {
+			return Deny
+		}
+		if p.allow != nil && p.allow.Match(r, ip, apiKey) {
+			decision = Allow
+		}
+	}
+	return decision
+}
+
+// function to stop syncing of all lists
+func (p *Policy) Stop() {
+	if p == nil {
+		return
+	}
+	if p.allow != nil {
+		p.allow.Stop()
+	}
+	if p.deny != nil {
+		p.deny.Stop()
+	}
+}
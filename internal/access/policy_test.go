@@ -0,0 +1,47 @@
+// policy_test.go
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/auth"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+func TestDecide(t *testing.T) {
+	global := NewPolicy(&utils.Access{
+		Deny: &utils.AccessList{IPs: []string{"203.0.113.0/24"}},
+	}, nil)
+	resource := NewPolicy(&utils.Access{
+		APIKeyHeader: "X-API-Key",
+		Allow:        &utils.AccessList{IPs: []string{"10.0.0.0/8", "203.0.113.5"}},
+		Deny:         &utils.AccessList{APIKeys: []string{auth.HashAPIKey("leaked-key")}},
+	}, nil)
+
+	tests := []struct {
+		name   string
+		ip     string
+		apiKey string
+		want   Decision
+	}{
+		{name: "unlisted", ip: "198.51.100.1", want: None},
+		{name: "allowed", ip: "10.1.1.1", want: Allow},
+		{name: "denied globally", ip: "203.0.113.9", want: Deny},
+		{name: "global deny wins over resource allow", ip: "203.0.113.5", want: Deny},
+		{name: "denied api key", ip: "198.51.100.1", apiKey: "leaked-key", want: Deny},
+		{name: "denied api key wins over allowed ip", ip: "10.1.1.1", apiKey: "leaked-key", want: Deny},
+		{name: "other api key", ip: "10.1.1.1", apiKey: "good-key", want: Allow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.apiKey != "" {
+				r.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if got := Decide(r, tt.ip, global, nil, resource); got != tt.want {
+				t.Errorf("got decision %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
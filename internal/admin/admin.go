@@ -0,0 +1,57 @@
+// admin.go
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/balancer"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/metrics"
+)
+
+// state of a destination as reported by admin api
+type destinationState struct {
+	Resource string `json:"resource"`
+	URL      string `json:"url"`
+	Weight   int    `json:"weight"`
+	Healthy  bool   `json:"healthy"`
+	Ejected  bool   `json:"ejected"`
+	Conns    int64  `json:"active_connections"`
+}
+
+// function to initialize router for admin api
+func NewHandler(pools []*balancer.Pool) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /destinations", destinationsHandler(pools))
+
+	return mux
+}
+
+// function to list health state of all destinations
+func destinationsHandler(pools []*balancer.Pool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		states := []destinationState{}
+		for _, pool := range pools {
+			for _, dest := range pool.Destinations() {
+				states = append(states, destinationState{
+					Resource: pool.Name,
+					URL:      dest.URL.String(),
+					Weight:   dest.Weight,
+					Healthy:  dest.Healthy(),
+					Ejected:  dest.Ejected(),
+					Conns:    dest.Conns(),
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, states)
+	}
+}
+
+// function to write json response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
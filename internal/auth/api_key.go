@@ -0,0 +1,164 @@
+// api_key.go
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// most unknown keys remembered at once, callers choose them so the cache is dropped once full
+const maxCachedMisses = 10000
+
+// cached lookup of a key in redis
+type cachedKey struct {
+	key     *utils.APIKey
+	expires time.Time
+}
+
+type APIKeyAuthenticator struct {
+	// header or query parameter carrying the key
+	header string
+	query  string
+
+	// mapping of sha256 hash -> key from config
+	keys map[string]*utils.APIKey
+
+	// redis hash of sha256 hash -> json metadata, empty if not used
+	redisHash string
+	rdb       *redis.Client
+
+	// recent redis lookups of known keys, bounded by the keys in redis
+	cache map[string]cachedKey
+
+	// recent redis lookups of unknown keys, bounded by max cached misses
+	misses map[string]time.Time
+
+	cacheTTL time.Duration
+	mu       sync.Mutex
+}
+
+// constructor to initialize api key authentication
+func NewAPIKeyAuthenticator(cfg *utils.APIKeyAuth, rdb *redis.Client) Authenticator {
+	a := &APIKeyAuthenticator{
+		header:    cfg.Header,
+		query:     cfg.Query,
+		keys:      make(map[string]*utils.APIKey),
+		redisHash: cfg.RedisHash,
+		rdb:       rdb,
+		cache:     make(map[string]cachedKey),
+		misses:    make(map[string]time.Time),
+		cacheTTL:  cfg.CacheTTL,
+	}
+	for i := range cfg.Keys {
+		key := &cfg.Keys[i]
+		hash := strings.ToLower(key.Hash)
+		if len(hash) != sha256.Size*2 {
+			log.Fatalf("api key hash of %s is not a sha256 hex digest", key.Owner)
+		}
+		a.keys[hash] = key
+	}
+	return a
+}
+
+// function to get sha256 hex digest of an api key as stored in config and redis
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// function to authenticate request by api key
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	raw := ""
+	if a.header != "" {
+		raw = r.Header.Get(a.header)
+	}
+	if raw == "" && a.query != "" {
+		raw = r.URL.Query().Get(a.query)
+	}
+	if raw == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	hash := HashAPIKey(raw)
+	key, exists := a.keys[hash]
+	if !exists {
+		key = a.lookup(r.Context(), hash)
+	}
+	if key == nil || (key.Enabled != nil && !*key.Enabled) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{
+		// prefix of the hash identifies the key without revealing it
+		Subject: hash[:16],
+		Owner:   key.Owner,
+		Tier:    key.Tier,
+	}, nil
+}
+
+// function to find key in redis, nil if not found
+func (a *APIKeyAuthenticator) lookup(ctx context.Context, hash string) *utils.APIKey {
+	if a.redisHash == "" {
+		return nil
+	}
+
+	// answering from cache if fresh
+	now := time.Now()
+	a.mu.Lock()
+	cached, exists := a.cache[hash]
+	missed, missing := a.misses[hash]
+	a.mu.Unlock()
+	if exists && now.Before(cached.expires) {
+		return cached.key
+	}
+	if missing && now.Before(missed) {
+		return nil
+	}
+
+	var key *utils.APIKey
+	data, err := a.rdb.HGet(ctx, a.redisHash, hash).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+	case err != nil:
+		// not caching failures of redis itself
+		slog.ErrorContext(ctx, "Error looking up api key", "error", err)
+		return nil
+	default:
+		key = &utils.APIKey{}
+		if err := json.Unmarshal(data, key); err != nil {
+			slog.ErrorContext(ctx, "Error decoding api key", "subject", hash[:16], "error", err)
+			key = nil
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key == nil {
+		// starting over once full so unknown keys cannot grow the cache
+		if len(a.misses) >= maxCachedMisses {
+			a.misses = make(map[string]time.Time)
+		}
+		a.misses[hash] = now.Add(a.cacheTTL)
+		delete(a.cache, hash)
+		return nil
+	}
+	delete(a.misses, hash)
+	a.cache[hash] = cachedKey{key: key, expires: now.Add(a.cacheTTL)}
+
+	return key
+}
+
+// function to stop the authenticator
+func (a *APIKeyAuthenticator) Stop() {}
@@ -0,0 +1,123 @@
+// api_key_test.go
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mr.HSet("gogate:keys", HashAPIKey("redis-key"), `{"owner":"bob","tier":"silver"}`)
+	mr.HSet("gogate:keys", HashAPIKey("revoked-key"), `{"owner":"eve","enabled":false}`)
+	mr.HSet("gogate:keys", HashAPIKey("broken-key"), `{"owner":`)
+
+	disabled := false
+	a := NewAPIKeyAuthenticator(&utils.APIKeyAuth{
+		Header: "X-API-Key",
+		Query:  "api_key",
+		Keys: []utils.APIKey{
+			{Hash: strings.ToUpper(HashAPIKey("config-key")), Owner: "alice", Tier: "gold"},
+			{Hash: HashAPIKey("disabled-key"), Owner: "mallory", Enabled: &disabled},
+		},
+		RedisHash: "gogate:keys",
+		CacheTTL:  time.Hour,
+	}, rdb)
+
+	tests := []struct {
+		name    string
+		header  string
+		query   string
+		owner   string
+		tier    string
+		subject string
+		err     error
+	}{
+		{name: "config key in header", header: "config-key", owner: "alice", tier: "gold", subject: HashAPIKey("config-key")[:16]},
+		{name: "config key in query", query: "config-key", owner: "alice", tier: "gold", subject: HashAPIKey("config-key")[:16]},
+		{name: "header before query", header: "config-key", query: "redis-key", owner: "alice", tier: "gold", subject: HashAPIKey("config-key")[:16]},
+		{name: "redis key", header: "redis-key", owner: "bob", tier: "silver", subject: HashAPIKey("redis-key")[:16]},
+		{name: "missing key", err: ErrMissingCredentials},
+		{name: "unknown key", header: "unknown-key", err: ErrInvalidCredentials},
+		{name: "disabled config key", header: "disabled-key", err: ErrInvalidCredentials},
+		{name: "disabled redis key", header: "revoked-key", err: ErrInvalidCredentials},
+		{name: "undecodable redis key", header: "broken-key", err: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/?api_key="+tt.query, nil)
+			if tt.header != "" {
+				r.Header.Set("X-API-Key", tt.header)
+			}
+			identity, err := a.Authenticate(r)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if identity.Owner != tt.owner || identity.Tier != tt.tier || identity.Subject != tt.subject {
+				t.Errorf("got identity %+v, want owner %s, tier %s and subject %s", identity, tt.owner, tt.tier, tt.subject)
+			}
+		})
+	}
+}
+
+func TestAPIKeyCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	mr.HSet("gogate:keys", HashAPIKey("known-key"), `{"owner":"bob"}`)
+
+	// function to authenticate a request carrying a key
+	authenticate := func(a Authenticator, key string) error {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-API-Key", key)
+		_, err := a.Authenticate(r)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		known    error
+		unknown  error
+	}{
+		// lookups are answered from cache until they expire
+		{name: "cached", cacheTTL: time.Hour, known: nil, unknown: ErrInvalidCredentials},
+		{name: "expired", cacheTTL: time.Nanosecond, known: ErrInvalidCredentials, unknown: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr.HSet("gogate:keys", HashAPIKey("known-key"), `{"owner":"bob"}`)
+			mr.HDel("gogate:keys", HashAPIKey("new-key"))
+			a := NewAPIKeyAuthenticator(&utils.APIKeyAuth{Header: "X-API-Key", RedisHash: "gogate:keys", CacheTTL: tt.cacheTTL}, rdb)
+			if err := authenticate(a, "known-key"); err != nil {
+				t.Fatalf("got error %v for known key", err)
+			}
+			if err := authenticate(a, "new-key"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("got error %v for unknown key, want %v", err, ErrInvalidCredentials)
+			}
+
+			// revoking the known key and adding the unknown one
+			mr.HDel("gogate:keys", HashAPIKey("known-key"))
+			mr.HSet("gogate:keys", HashAPIKey("new-key"), `{"owner":"carol"}`)
+			time.Sleep(time.Millisecond)
+			if err := authenticate(a, "known-key"); !errors.Is(err, tt.known) {
+				t.Errorf("got error %v for revoked key, want %v", err, tt.known)
+			}
+			if err := authenticate(a, "new-key"); !errors.Is(err, tt.unknown) {
+				t.Errorf("got error %v for added key, want %v", err, tt.unknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// auth.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// errors surfaced as 401 responses
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// authenticator interface to support all common functions of an authentication method
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+	Stop()
+}
+
+// authenticated caller
+type Identity struct {
+	// stable id of the caller used as limiter key
+	Subject string
+
+	// metadata of the caller
+	Owner string
+	Tier  string
+
+	// claims of the token, nil for api keys
+	Claims map[string]any
+
+	// headers forwarded to the destination
+	Headers map[string]string
+}
+
+// key for identity in request context
+type identityKey struct{}
+
+// function to attach identity to request
+func WithIdentity(r *http.Request, identity *Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, identity))
+}
+
+// function to get identity of request, nil if not authenticated
+func FromRequest(r *http.Request) *Identity {
+	identity, _ := r.Context().Value(identityKey{}).(*Identity)
+	return identity
+}
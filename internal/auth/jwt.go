@@ -0,0 +1,219 @@
+// jwt.go
+package auth
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type JWTAuthenticator struct {
+	// shared secret for HS256
+	secret []byte
+
+	// mapping of kid -> public key for RS256 and ES256
+	keys map[string]crypto.PublicKey
+	mu   sync.RWMutex
+
+	// file of the key set, reloaded when modified
+	jwksFile string
+	modTime  time.Time
+
+	// options of the parser
+	parserOptions []jwt.ParserOption
+
+	// claim selecting the tier of the caller
+	tierClaim string
+
+	// mapping of claim -> header forwarded to destination
+	forwardClaims map[string]string
+
+	// context for closure
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// constructor to initialize jwt authentication
+func NewJWTAuthenticator(cfg *utils.JWTAuth) Authenticator {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &JWTAuthenticator{
+		secret:        []byte(cfg.Secret),
+		keys:          make(map[string]crypto.PublicKey),
+		jwksFile:      cfg.JWKSFile,
+		tierClaim:     cfg.TierClaim,
+		forwardClaims: cfg.ForwardClaims,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	// rejecting unexpected algorithms so keys cannot be misused
+	a.parserOptions = []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		a.parserOptions = append(a.parserOptions, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		a.parserOptions = append(a.parserOptions, jwt.WithAudience(cfg.Audience))
+	}
+
+	// loading static key set
+	if cfg.JWKS != "" {
+		keys, err := parseJWKS([]byte(cfg.JWKS))
+		if err != nil {
+			log.Fatalf("invalid jwks %v", err)
+		}
+		a.keys = keys
+	}
+
+	// loading key set from file and reloading it on rotation
+	if a.jwksFile != "" {
+		if err := a.load(); err != nil {
+			log.Fatalf("unable to load jwks %v", err)
+		}
+		go a.watch(cfg.ReloadInterval)
+	}
+
+	return a
+}
+
+// function to authenticate request by bearer token
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || raw == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, a.keyFunc, a.parserOptions...)
+	if err != nil {
+		slog.InfoContext(r.Context(), "Invalid token", "error", err)
+		return nil, ErrInvalidCredentials
+	}
+
+	identity := &Identity{
+		Claims:  claims,
+		Headers: make(map[string]string),
+	}
+	identity.Subject, _ = claims.GetSubject()
+	identity.Owner = Claim(claims, "tenant")
+	if a.tierClaim != "" {
+		identity.Tier = Claim(claims, a.tierClaim)
+	}
+	for claim, header := range a.forwardClaims {
+		identity.Headers[header] = Claim(claims, claim)
+	}
+
+	return identity, nil
+}
+
+// function to get verification key as per algorithm and kid of token
+func (a *JWTAuthenticator) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(a.secret) == 0 {
+			return nil, fmt.Errorf("no secret for %s", token.Method.Alg())
+		}
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	key, exists := a.keys[kid]
+	if !exists {
+		// single key sets are used even without kid
+		if kid == "" && len(a.keys) == 1 {
+			for _, key := range a.keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// function to load key set from file
+func (a *JWTAuthenticator) load() error {
+	info, err := os.Stat(a.jwksFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(a.jwksFile)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.mu.Unlock()
+	a.modTime = info.ModTime()
+	return nil
+}
+
+// function to reload key set file periodically if modified
+func (a *JWTAuthenticator) watch(interval time.Duration) {
+
+	// initialize ticker to tick every interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+			info, err := os.Stat(a.jwksFile)
+			if err != nil || info.ModTime().Equal(a.modTime) {
+				continue
+			}
+			if err := a.load(); err != nil {
+				log.Printf("Error reloading jwks: %v", err)
+				continue
+			}
+			log.Println("JWKS reloaded")
+
+		// returning from function if context is cancelled
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
+
+// function to stop reloading of key set
+func (a *JWTAuthenticator) Stop() {
+	a.cancel()
+}
+
+// function to get claim as string, lists are joined by space
+func Claim(claims map[string]any, name string) string {
+	switch v := claims[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, part := range v {
+			parts = append(parts, fmt.Sprint(part))
+		}
+		return strings.Join(parts, " ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
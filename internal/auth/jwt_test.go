@@ -0,0 +1,345 @@
+// jwt_test.go
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// function to get base64url encoding of a big endian integer padded to size bytes
+func encodeBigInt(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
+
+// function to get json web key set of public keys by kid
+func testJWKS(t *testing.T, keys map[string]any) string {
+	t.Helper()
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA", Kid: kid, Use: "sig",
+				N: encodeBigInt(key.N, key.Size()),
+				E: encodeBigInt(big.NewInt(int64(key.E)), 3),
+			})
+		case *ecdsa.PrivateKey:
+			set.Keys = append(set.Keys, jwk{
+				Kty: "EC", Kid: kid, Crv: "P-256",
+				X: encodeBigInt(key.X, 32),
+				Y: encodeBigInt(key.Y, 32),
+			})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// function to sign claims with a method and key, setting kid if not empty
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// function to authenticate a request carrying a bearer token
+func authenticateToken(a Authenticator, token string) (*Identity, error) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return a.Authenticate(r)
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	// function to get claims valid for a minute with extra claims
+	valid := func(extra jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"sub": "user-1",
+			"iss": "https://issuer.example",
+			"aud": "gogate",
+			"exp": now.Add(time.Minute).Unix(),
+		}
+		for name, value := range extra {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+	none := sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", valid(nil))
+
+	tests := []struct {
+		name  string
+		cfg   utils.JWTAuth
+		token string
+		err   error
+	}{
+		{
+			name:  "rsa key",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(nil)),
+		},
+		{
+			name:  "ec key",
+			cfg:   utils.JWTAuth{Algorithms: []string{"ES256"}},
+			token: sign(t, jwt.SigningMethodES256, ecKey, "ec", valid(nil)),
+		},
+		{
+			name:  "secret",
+			cfg:   utils.JWTAuth{Algorithms: []string{"HS256"}, Secret: "secret"},
+			token: sign(t, jwt.SigningMethodHS256, []byte("secret"), "", valid(nil)),
+		},
+		{
+			name:  "wrong secret",
+			cfg:   utils.JWTAuth{Algorithms: []string{"HS256"}, Secret: "secret"},
+			token: sign(t, jwt.SigningMethodHS256, []byte("guess"), "", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "hmac not pinned",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Secret: "secret"},
+			token: sign(t, jwt.SigningMethodHS256, []byte("secret"), "", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "ec not pinned",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodES256, ecKey, "ec", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "unsigned",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: none,
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "unsigned with none allowed",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256", "none"}},
+			token: none,
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "unknown kid",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "missing", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "key of another kid",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, otherKey, "rsa", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "no kid with many keys",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "expired",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "expired within leeway",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Leeway: 2 * time.Minute},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+		},
+		{
+			name:  "no expiry",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"exp": nil})),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "not yet valid",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "not yet valid within leeway",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Leeway: 2 * time.Minute},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"nbf": now.Add(time.Minute).Unix()})),
+		},
+		{
+			name:  "issuer",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Issuer: "https://issuer.example"},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(nil)),
+		},
+		{
+			name:  "wrong issuer",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Issuer: "https://other.example"},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "audience in list",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Audience: "gogate"},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(jwt.MapClaims{"aud": []string{"billing", "gogate"}})),
+		},
+		{
+			name:  "wrong audience",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}, Audience: "billing"},
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", valid(nil)),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "malformed",
+			cfg:   utils.JWTAuth{Algorithms: []string{"RS256"}},
+			token: "not.a.token",
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name: "missing",
+			cfg:  utils.JWTAuth{Algorithms: []string{"RS256"}},
+			err:  ErrMissingCredentials,
+		},
+	}
+	jwks := testJWKS(t, map[string]any{"rsa": rsaKey, "ec": ecKey})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.JWKS = jwks
+			a := NewJWTAuthenticator(&tt.cfg)
+			t.Cleanup(a.Stop)
+
+			identity, err := authenticateToken(a, tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && identity.Subject != "user-1" {
+				t.Errorf("got subject %q, want user-1", identity.Subject)
+			}
+		})
+	}
+}
+
+func TestJWTIdentity(t *testing.T) {
+	a := NewJWTAuthenticator(&utils.JWTAuth{
+		Algorithms:    []string{"HS256"},
+		Secret:        "secret",
+		TierClaim:     "plan",
+		ForwardClaims: map[string]string{"scope": "X-Scope", "sub": "X-User"},
+	})
+	t.Cleanup(a.Stop)
+
+	token := sign(t, jwt.SigningMethodHS256, []byte("secret"), "", jwt.MapClaims{
+		"sub":    "user-1",
+		"tenant": "acme",
+		"plan":   "gold",
+		"scope":  []string{"read", "write"},
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	})
+	identity, err := authenticateToken(a, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "user-1" || identity.Owner != "acme" || identity.Tier != "gold" {
+		t.Errorf("got identity %+v, want subject user-1, owner acme and tier gold", identity)
+	}
+	if got := identity.Headers["X-Scope"]; got != "read write" {
+		t.Errorf("got scope header %q, want %q", got, "read write")
+	}
+	if got := identity.Headers["X-User"]; got != "user-1" {
+		t.Errorf("got user header %q, want user-1", got)
+	}
+}
+
+func TestJWKSReload(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(testJWKS(t, map[string]any{"old": oldKey})), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := NewJWTAuthenticator(&utils.JWTAuth{
+		Algorithms:     []string{"RS256"},
+		JWKSFile:       path,
+		ReloadInterval: 10 * time.Millisecond,
+	})
+	t.Cleanup(a.Stop)
+
+	claims := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Minute).Unix()}
+	oldToken := sign(t, jwt.SigningMethodRS256, oldKey, "old", claims)
+	newToken := sign(t, jwt.SigningMethodRS256, newKey, "new", claims)
+	if _, err := authenticateToken(a, oldToken); err != nil {
+		t.Fatalf("got error %v for token of loaded key", err)
+	}
+	if _, err := authenticateToken(a, newToken); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("got error %v for token of unknown key, want %v", err, ErrInvalidCredentials)
+	}
+
+	// rotating the key set, moving the modification time so the change is seen on coarse clocks
+	if err := os.WriteFile(path, []byte(testJWKS(t, map[string]any{"new": newKey})), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := authenticateToken(a, newToken); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("rotated key set not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := authenticateToken(a, oldToken); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("got error %v for token of removed key, want %v", err, ErrInvalidCredentials)
+	}
+}
@@ -0,0 +1,148 @@
+// balancer.go
+package balancer
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// balancer interface to support all common functions of a load balancer
+type Balancer interface {
+	Next(key string) *Destination
+}
+
+// destination blueprint
+type Destination struct {
+	// target url of the backend
+	URL *url.URL
+
+	// relative share of traffic
+	Weight int
+
+	// handler forwarding requests to the backend
+	Handler http.Handler
+
+	// transport used by health probes, default if nil
+	Transport http.RoundTripper
+
+	// number of requests currently being served
+	conns atomic.Int64
+
+	// result of active health checks
+	healthy atomic.Bool
+
+	// consecutive failed requests and time till which destination is ejected
+	fails        atomic.Int64
+	ejectedUntil atomic.Int64
+
+	// passive health check settings
+	passive *utils.PassiveHealthCheck
+}
+
+// constructor to initialize destination
+func NewDestination(url *url.URL, weight int, handler http.Handler) *Destination {
+	if weight <= 0 {
+		weight = 1
+	}
+	d := &Destination{
+		URL:     url,
+		Weight:  weight,
+		Handler: handler,
+	}
+	d.healthy.Store(true)
+	return d
+}
+
+// function to get number of requests currently being served
+func (d *Destination) Conns() int64 {
+	return d.conns.Load()
+}
+
+// pool of destinations behind a single resource
+type Pool struct {
+	// name of the resource
+	Name string
+
+	// all destinations
+	dests []*Destination
+
+	// balancing strategy
+	balancer Balancer
+
+	// function to extract client key from request
+	key func(*http.Request) string
+
+	// context for closure
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// constructor to initialize pool
+func NewPool(name string, strategy string, dests []*Destination, key func(*http.Request) string, health *utils.HealthCheck) *Pool {
+	algo, exists := Balancers[strategy]
+	if !exists {
+		log.Fatalf("no such balancer %s", strategy)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		Name:     name,
+		dests:    dests,
+		balancer: algo(dests),
+		key:      key,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	for _, dest := range dests {
+		dest.passive = health.Passive
+		registerMetrics(name, dest)
+
+		// starting the active health checks as go routines
+		if health.Active != nil {
+			go dest.probe(ctx, health.Active)
+		}
+	}
+
+	return p
+}
+
+// function to get all destinations in pool
+func (p *Pool) Destinations() []*Destination {
+	return p.dests
+}
+
+// function to stop the health checks
+func (p *Pool) Stop() {
+	p.cancel()
+}
+
+// function to forward request to the next destination
+func (p *Pool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dest := p.balancer.Next(p.key(r))
+	if dest == nil {
+		http.Error(w, "503 No Healthy Upstream", http.StatusServiceUnavailable)
+		return
+	}
+
+	// tracking active connections for the whole lifetime of the request
+	dest.conns.Add(1)
+	defer dest.conns.Add(-1)
+
+	dest.Handler.ServeHTTP(w, r)
+}
+
+// all balancers
+// alias for the common function
+type BalancerFunc func(dests []*Destination) Balancer
+
+var Balancers = map[string]BalancerFunc{
+	"ROUND-ROBIN":       NewRoundRobin,
+	"WEIGHTED":          NewWeighted,
+	"LEAST-CONNECTIONS": NewLeastConnections,
+	"CONSISTENT-HASH":   NewConsistentHash,
+}
@@ -0,0 +1,59 @@
+// consistent_hash.go
+package balancer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// no of points on the ring per unit weight
+const replicas = 100
+
+type ConsistentHash struct {
+	// sorted hashes of all points on the ring
+	ring []uint32
+
+	// mapping of point on the ring -> destination
+	points map[uint32]*Destination
+}
+
+// constructor to initialize consistent hash ring
+func NewConsistentHash(dests []*Destination) Balancer {
+	ch := &ConsistentHash{
+		points: make(map[uint32]*Destination),
+	}
+
+	// placing every destination on the ring as per its weight
+	for _, dest := range dests {
+		for i := 0; i < replicas*dest.Weight; i++ {
+			hash := crc32.ChecksumIEEE([]byte(dest.URL.String() + "#" + strconv.Itoa(i)))
+			if _, exists := ch.points[hash]; exists {
+				continue
+			}
+			ch.points[hash] = dest
+			ch.ring = append(ch.ring, hash)
+		}
+	}
+	sort.Slice(ch.ring, func(i, j int) bool { return ch.ring[i] < ch.ring[j] })
+
+	return ch
+}
+
+// function to pick the destination owning the client key
+func (ch *ConsistentHash) Next(key string) *Destination {
+	if len(ch.ring) == 0 {
+		return nil
+	}
+
+	// first available point clockwise from the hash of the key
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(ch.ring), func(i int) bool { return ch.ring[i] >= hash })
+	for i := range ch.ring {
+		dest := ch.points[ch.ring[(start+i)%len(ch.ring)]]
+		if dest.Available() {
+			return dest
+		}
+	}
+	return nil
+}
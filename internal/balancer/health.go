@@ -0,0 +1,106 @@
+// health.go
+package balancer
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/metrics"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// function to check if destination can receive traffic
+func (d *Destination) Available() bool {
+	return d.healthy.Load() && time.Now().UnixNano() >= d.ejectedUntil.Load()
+}
+
+// function to check result of the active health probes
+func (d *Destination) Healthy() bool {
+	return d.healthy.Load()
+}
+
+// function to check if destination is ejected by passive health checks
+func (d *Destination) Ejected() bool {
+	return time.Now().UnixNano() < d.ejectedUntil.Load()
+}
+
+// function to record a failed request to the destination
+func (d *Destination) ReportFailure() {
+	if d.passive == nil || d.passive.MaxFails <= 0 {
+		return
+	}
+
+	// ejecting the destination after too many consecutive failures
+	if d.fails.Add(1) >= int64(d.passive.MaxFails) {
+		d.fails.Store(0)
+		d.ejectedUntil.Store(time.Now().Add(d.passive.EjectDuration).UnixNano())
+		log.Printf("Destination %s ejected for %s", d.URL, d.passive.EjectDuration)
+	}
+}
+
+// function to record a successful request to the destination
+func (d *Destination) ReportSuccess() {
+	d.fails.Store(0)
+}
+
+// function to probe the destination periodically
+func (d *Destination) probe(ctx context.Context, active *utils.ActiveHealthCheck) {
+
+	// initialize ticker to tick every interval
+	ticker := time.NewTicker(active.Interval)
+	defer ticker.Stop()
+
+	client := &http.Client{Timeout: active.Timeout, Transport: d.Transport}
+	target := d.URL.JoinPath(active.Path).String()
+
+	// consecutive results in the same direction
+	passes, fails := 0, 0
+
+	for {
+		select {
+
+		// probing as per interval
+		case <-ticker.C:
+			if d.check(ctx, client, target) {
+				passes, fails = passes+1, 0
+			} else {
+				passes, fails = 0, fails+1
+			}
+
+			// flipping state once threshold is reached
+			if !d.healthy.Load() && passes >= active.HealthyThreshold {
+				d.healthy.Store(true)
+				log.Printf("Destination %s is healthy", d.URL)
+			}
+			if d.healthy.Load() && fails >= active.UnhealthyThreshold {
+				d.healthy.Store(false)
+				log.Printf("Destination %s is unhealthy", d.URL)
+			}
+
+		// returning from function if context is cancelled
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// function to run a single health probe
+func (d *Destination) check(ctx context.Context, client *http.Client, target string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode >= 200 && res.StatusCode < 400
+}
+
+// function to expose destination state as metrics
+func registerMetrics(resource string, d *Destination) {
+	metrics.RegisterDestination(resource, d.URL.String(), d.Available, d.Conns)
+}
@@ -0,0 +1,40 @@
+// least_connections.go
+package balancer
+
+import "sync/atomic"
+
+type LeastConnections struct {
+	// all destinations
+	dests []*Destination
+
+	// offset to spread ties across destinations
+	next atomic.Uint64
+}
+
+// constructor to initialize least connections
+func NewLeastConnections(dests []*Destination) Balancer {
+	return &LeastConnections{dests: dests}
+}
+
+// function to pick destination with least active connections relative to its weight
+func (lc *LeastConnections) Next(key string) *Destination {
+	if len(lc.dests) == 0 {
+		return nil
+	}
+
+	// starting from a rotating offset so ties do not always go to the first destination
+	start := int(lc.next.Add(1) % uint64(len(lc.dests)))
+
+	var best *Destination
+	for i := range lc.dests {
+		dest := lc.dests[(start+i)%len(lc.dests)]
+		if !dest.Available() {
+			continue
+		}
+		// comparing conns/weight without division
+		if best == nil || dest.Conns()*int64(best.Weight) < best.Conns()*int64(dest.Weight) {
+			best = dest
+		}
+	}
+	return best
+}
@@ -0,0 +1,29 @@
+// round_robin.go
+package balancer
+
+import "sync/atomic"
+
+type RoundRobin struct {
+	// all destinations
+	dests []*Destination
+
+	// counter of requests served so far
+	next atomic.Uint64
+}
+
+// constructor to initialize round robin
+func NewRoundRobin(dests []*Destination) Balancer {
+	return &RoundRobin{dests: dests}
+}
+
+// function to pick available destinations one after the other
+func (rr *RoundRobin) Next(key string) *Destination {
+	for range rr.dests {
+		n := rr.next.Add(1) - 1
+		dest := rr.dests[n%uint64(len(rr.dests))]
+		if dest.Available() {
+			return dest
+		}
+	}
+	return nil
+}
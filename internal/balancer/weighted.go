@@ -0,0 +1,47 @@
+// weighted.go
+package balancer
+
+import "sync"
+
+type Weighted struct {
+	// all destinations
+	dests []*Destination
+
+	// current weight of every destination
+	current []int
+
+	mu sync.Mutex
+}
+
+// constructor to initialize weighted round robin
+func NewWeighted(dests []*Destination) Balancer {
+	return &Weighted{
+		dests:   dests,
+		current: make([]int, len(dests)),
+	}
+}
+
+// function to pick destinations in proportion to their weights
+// following smooth weighted round robin so heavy destinations are not picked in bursts
+func (w *Weighted) Next(key string) *Destination {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// only available destinations take part in the round
+	best, total := -1, 0
+	for i, dest := range w.dests {
+		if !dest.Available() {
+			continue
+		}
+		w.current[i] += dest.Weight
+		total += dest.Weight
+		if best == -1 || w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	if best == -1 {
+		return nil
+	}
+	w.current[best] -= total
+	return w.dests[best]
+}
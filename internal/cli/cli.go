@@ -0,0 +1,105 @@
+// cli.go
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// default path of the config file
+const defaultConfig = "config/config.yaml"
+
+// subcommand of the command line
+type command struct {
+	// one line description shown in usage
+	summary string
+
+	// function to run the subcommand with its arguments
+	run func(args []string) error
+}
+
+// all subcommands, key = name
+var commands = map[string]command{
+	"serve":    {"start the gateway", serve},
+	"validate": {"check a config file", validate},
+	"inspect":  {"show live state of a resource in redis", inspect},
+	"reset":    {"clear state of a resource, method or client in redis", reset},
+	"simulate": {"replay a traffic pattern against a strategy offline", simulate},
+}
+
+// function to run command line with arguments excluding program name, returns exit code
+func Run(args []string) int {
+	// serving with default config if no subcommand is given
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	cmd, exists := commands[args[0]]
+	if !exists {
+		usage(os.Stderr)
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			return 0
+		}
+		return 2
+	}
+
+	if err := cmd.run(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// function to print usage of all subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gogate <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gogate <command> -h' for flags of a command.")
+}
+
+// function to initialize flag set of a subcommand
+func newFlagSet(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gogate %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// function to add flags of the redis connection, returns function connecting to it
+func redisFlags(fs *flag.FlagSet) func() *redis.Client {
+	config := fs.String("config", "", "take redis settings from a config file")
+	address := fs.String("redis", "", "address of redis (default localhost:6379)")
+	return func() *redis.Client {
+		cfg := utils.Redis{Address: "localhost:6379"}
+		if *config != "" {
+			cfg = utils.NewConfiguration(*config).Redis
+		}
+		if *address != "" {
+			cfg.Address = *address
+		}
+		return utils.InitRedis(&cfg)
+	}
+}
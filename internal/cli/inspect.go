@@ -0,0 +1,95 @@
+// inspect.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// function to show live state of a resource in redis
+func inspect(args []string) error {
+	fs := newFlagSet("inspect", "<resource>")
+	client := fs.String("client", "", "only show state of a client key")
+	connect := redisFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("resource is required")
+	}
+	resource := fs.Arg(0)
+
+	// state of every kind of limit of the resource
+	rdb := connect()
+	defer rdb.Close()
+	keys, err := keyFilter{resource: resource, client: *client}.scan(rdb)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Printf("no state for resource %s\n", resource)
+		return nil
+	}
+	sort.Strings(keys)
+
+	ctx := context.Background()
+	fmt.Printf("%-72s %-6s %-12s %s\n", "KEY", "TYPE", "VALUE", "TTL")
+	for _, key := range keys {
+		kind, err := rdb.Type(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		// summarizing value as per type
+		var value string
+		switch kind {
+		case "string":
+			value = rdb.Get(ctx, key).Val()
+		case "list":
+			value = fmt.Sprintf("%d items", rdb.LLen(ctx, key).Val())
+		case "zset":
+			value = fmt.Sprintf("%d members", rdb.ZCard(ctx, key).Val())
+		case "hash":
+			value = fmt.Sprintf("%d fields", rdb.HLen(ctx, key).Val())
+		case "none":
+			// expired while scanning
+			continue
+		}
+
+		ttl := "-"
+		if d := rdb.PTTL(ctx, key).Val(); d > 0 {
+			ttl = d.Round(time.Millisecond).String()
+		}
+		fmt.Printf("%-72s %-6s %-12s %s\n", key, kind, value, ttl)
+	}
+	return nil
+}
+
+// function to get all keys matching a pattern
+func scanKeys(rdb *redis.Client, pattern string) ([]string, error) {
+	var keys []string
+	iter := rdb.Scan(context.Background(), 0, pattern, 1000).Iterator()
+	for iter.Next(context.Background()) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// function to escape special characters of redis glob patterns
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+// keys.go
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// filter of keys the gateway keeps in redis for a resource, empty fields match any value
+// keys are matched against their exact layout, client keys may contain colons so patterns alone can not tell them apart
+//
+//	gogate:limit:<resource>:<tier|->:<method>:<client|-><suffix>
+//	gogate:bandwidth:<resource>:<method>[:<client>]:<upload|download>
+//	gogate:penalty:<resource>:<client>:<ban|strikes|offenses>
+//	gogate:streams:<resource>:<client>
+type keyFilter struct {
+	resource string
+	tier     string
+	method   string
+	client   string
+}
+
+// function to get patterns to scan for keys of the resource, scanned keys are checked with match
+func (f keyFilter) patterns() []string {
+	var patterns []string
+	for _, family := range []string{"limit", "bandwidth", "penalty", "streams"} {
+		patterns = append(patterns, "gogate:"+family+":"+escapeGlob(f.resource)+":*")
+	}
+	return patterns
+}
+
+// function to check whether a key of the gateway belongs to the filter
+func (f keyFilter) match(key string) bool {
+	family, rest, _ := strings.Cut(strings.TrimPrefix(key, "gogate:"), ":")
+	rest, found := strings.CutPrefix(rest, f.resource+":")
+	if !found {
+		return false
+	}
+
+	switch family {
+	case "limit":
+		tier, rest, found := strings.Cut(rest, ":")
+		if !found || !matches(f.tier, tier) {
+			return false
+		}
+		method, _, found := strings.Cut(rest, ":")
+		if !found || !matches(f.method, method) {
+			return false
+		}
+		return f.client == "" || slices.Contains(limiter.Keys(utils.LimitID(f.resource, tier, method)+":"+f.client), key)
+
+	// bandwidth is per method and only per client if partitioned
+	case "bandwidth":
+		if f.tier != "" {
+			return false
+		}
+		method, rest, found := strings.Cut(rest, ":")
+		if !found || !matches(f.method, method) {
+			return false
+		}
+		for _, direction := range []string{"upload", "download"} {
+			if rest == direction {
+				return f.client == ""
+			}
+			if client, found := strings.CutSuffix(rest, ":"+direction); found {
+				return f.client == "" || client == f.client
+			}
+		}
+		return false
+
+	// bans and streams are not per tier or method
+	case "penalty":
+		if f.tier != "" || f.method != "" {
+			return false
+		}
+		if f.client == "" {
+			return true
+		}
+		return slices.Contains(limiter.PenaltyKeys("gogate:penalty:"+f.resource+":"+f.client), key)
+	case "streams":
+		if f.tier != "" || f.method != "" {
+			return false
+		}
+		return f.client == "" || rest == f.client
+	}
+	return false
+}
+
+// function to get all keys of the filter
+func (f keyFilter) scan(rdb *redis.Client) ([]string, error) {
+	var keys []string
+	for _, pattern := range f.patterns() {
+		scanned, err := scanKeys(rdb, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range scanned {
+			if f.match(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// function to check a part of a key against a filter, any value if not set
+func matches(filter string, value string) bool {
+	return filter == "" || filter == value
+}
@@ -0,0 +1,124 @@
+// keys_test.go
+package cli
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKeyFilter(t *testing.T) {
+	keys := []string{
+		"gogate:limit:api:-:GET:-",
+		"gogate:limit:api:-:GET:10.0.0.1",
+		"gogate:limit:api:-:GET:10.0.0.1:slot",
+		"gogate:limit:api:gold:POST:10.0.0.1:curr",
+		"gogate:limit:api:-:GET:110.0.0.1",
+		"gogate:limit:api:-:GET:2001:db8::1",
+		"gogate:limit:api:-:GET:2001:db8::1:prev",
+		"gogate:limit:api-v2:-:GET:10.0.0.1",
+		"gogate:limit:x:api:GET:10.0.0.1",
+		"gogate:bandwidth:api:GET:download",
+		"gogate:bandwidth:api:PUT:10.0.0.1:upload",
+		"gogate:bandwidth:stream:7f3c",
+		"gogate:penalty:api:10.0.0.1:ban",
+		"gogate:penalty:api:10.0.0.10:strikes",
+		"gogate:streams:api:10.0.0.1",
+		"gogate:streams:api-v2:10.0.0.1",
+		"gogate:decision:api:path:5:/home",
+	}
+
+	tests := []struct {
+		name   string
+		filter keyFilter
+		want   []string
+	}{
+		{
+			name:   "resource",
+			filter: keyFilter{resource: "api"},
+			want: []string{
+				"gogate:limit:api:-:GET:-",
+				"gogate:limit:api:-:GET:10.0.0.1",
+				"gogate:limit:api:-:GET:10.0.0.1:slot",
+				"gogate:limit:api:gold:POST:10.0.0.1:curr",
+				"gogate:limit:api:-:GET:110.0.0.1",
+				"gogate:limit:api:-:GET:2001:db8::1",
+				"gogate:limit:api:-:GET:2001:db8::1:prev",
+				"gogate:bandwidth:api:GET:download",
+				"gogate:bandwidth:api:PUT:10.0.0.1:upload",
+				"gogate:penalty:api:10.0.0.1:ban",
+				"gogate:penalty:api:10.0.0.10:strikes",
+				"gogate:streams:api:10.0.0.1",
+			},
+		},
+		{
+			name:   "client",
+			filter: keyFilter{resource: "api", client: "10.0.0.1"},
+			want: []string{
+				"gogate:limit:api:-:GET:10.0.0.1",
+				"gogate:limit:api:-:GET:10.0.0.1:slot",
+				"gogate:limit:api:gold:POST:10.0.0.1:curr",
+				"gogate:bandwidth:api:PUT:10.0.0.1:upload",
+				"gogate:penalty:api:10.0.0.1:ban",
+				"gogate:streams:api:10.0.0.1",
+			},
+		},
+		{
+			name:   "client with colons",
+			filter: keyFilter{resource: "api", client: "2001:db8::1"},
+			want: []string{
+				"gogate:limit:api:-:GET:2001:db8::1",
+				"gogate:limit:api:-:GET:2001:db8::1:prev",
+			},
+		},
+		{
+			name:   "method",
+			filter: keyFilter{resource: "api", method: "POST"},
+			want:   []string{"gogate:limit:api:gold:POST:10.0.0.1:curr"},
+		},
+		{
+			name:   "default tier",
+			filter: keyFilter{resource: "api", tier: "-", client: "10.0.0.1"},
+			want: []string{
+				"gogate:limit:api:-:GET:10.0.0.1",
+				"gogate:limit:api:-:GET:10.0.0.1:slot",
+			},
+		},
+		{
+			name:   "unpartitioned",
+			filter: keyFilter{resource: "api", client: "-"},
+			want:   []string{"gogate:limit:api:-:GET:-"},
+		},
+		{
+			name:   "glob characters",
+			filter: keyFilter{resource: "api*"},
+		},
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	for _, key := range keys {
+		if err := rdb.Set(context.Background(), key, 1, 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.scan(rdb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("got keys %q, want %q", got, want)
+			}
+		})
+	}
+}
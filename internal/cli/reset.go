@@ -0,0 +1,43 @@
+// reset.go
+package cli
+
+import (
+	"context"
+	"fmt"
+)
+
+// function to clear state of a resource, method or client in redis
+func reset(args []string) error {
+	fs := newFlagSet("reset", "<resource>")
+	method := fs.String("method", "", "only clear limits of a http request method")
+	tier := fs.String("tier", "", "only clear limits of a tier, - for the default limits")
+	client := fs.String("client", "", "only clear state of a client key")
+	connect := redisFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("resource is required")
+	}
+	filter := keyFilter{resource: fs.Arg(0), tier: *tier, method: *method, client: *client}
+
+	rdb := connect()
+	defer rdb.Close()
+
+	keys, err := filter.scan(rdb)
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	if len(keys) > 0 {
+		n, err := rdb.Del(context.Background(), keys...).Result()
+		if err != nil {
+			return err
+		}
+		deleted = int(n)
+	}
+
+	fmt.Printf("cleared %d keys\n", deleted)
+	return nil
+}
@@ -0,0 +1,16 @@
+// serve.go
+package cli
+
+import "github.com/Sp92535/GoGate-RateLimiter/internal/proxy"
+
+// function to start the gateway
+func serve(args []string) error {
+	fs := newFlagSet("serve", "")
+	config := fs.String("config", defaultConfig, "path of the config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	proxy.Run(*config)
+	return nil
+}
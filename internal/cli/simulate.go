@@ -0,0 +1,427 @@
+// simulate.go
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// phase of a traffic pattern sending requests at a steady rate
+type phase struct {
+	// interval between two requests, 0 if idle
+	interval time.Duration
+
+	// length of the phase
+	duration time.Duration
+}
+
+// outcome of a simulated request
+type outcome struct {
+	sent     time.Duration
+	accepted bool
+
+	// time the request reached the destination, -1 if never
+	served time.Duration
+}
+
+// function to replay a traffic pattern against a strategy on an in-memory redis
+func simulate(args []string) error {
+	fs := newFlagSet("simulate", "")
+	config := fs.String("config", "", "take the limit from a config file")
+	resource := fs.String("resource", "", "resource of the limit in the config file")
+	method := fs.String("method", "GET", "http request method of the limit in the config file")
+	tier := fs.String("tier", "", "tier of the limit in the config file")
+	strategy := fs.String("strategy", "TOKEN-BUCKET", "strategy of the limit")
+	rate := fs.String("rate", "10/s", "rate of the limit")
+	capacity := fs.Int("capacity", 10, "capacity of bucket strategies")
+	mode := fs.String("mode", "reject", "reject or delay throttled requests")
+	maxDelay := fs.Duration("max-delay", time.Second, "longest delay of a request in delay mode")
+	pattern := fs.String("pattern", "20/s:3s", "comma separated phases of traffic as rate:duration, e.g. 50/s:2s,0/s:1s,5/s:5s")
+	cost := fs.Int("cost", 1, "cost of every request")
+	step := fs.Duration("step", time.Second, "interval of the timeline")
+	verbose := fs.Bool("verbose", false, "print every request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// getting the limit from config or flags
+	var rateLimit *utils.RateLimit
+	if *config != "" {
+		var err error
+		rateLimit, err = configuredLimit(*config, *resource, *tier, *method)
+		if err != nil {
+			return err
+		}
+	} else {
+		rateLimit = &utils.RateLimit{
+			Strategy: *strategy,
+			Rate:     *rate,
+			Capacity: *capacity,
+			Mode:     *mode,
+			MaxDelay: *maxDelay,
+		}
+		if err := rateLimit.Parse(); err != nil {
+			return err
+		}
+	}
+	algo, exists := limiter.Steppers[rateLimit.Strategy]
+	if !exists {
+		return fmt.Errorf("no such strategy %s", rateLimit.Strategy)
+	}
+
+	phases, err := parsePattern(*pattern)
+	if err != nil {
+		return err
+	}
+
+	// running the scripts on an in-memory redis
+	mr, err := miniredis.Run()
+	if err != nil {
+		return err
+	}
+	defer mr.Close()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	if err := limiter.Setup(context.Background(), rdb); err != nil {
+		return err
+	}
+
+	// ids are not shared with any other limiter
+	limit := *rateLimit
+	limit.ID = ""
+	s := newSimulation(mr, algo(rdb, &limit), &limit, *cost)
+	defer s.limiter.Stop()
+
+	fmt.Printf("simulating %s %s with %s\n", limit.Strategy, limit.Rate, *pattern)
+
+	sends, length := sendTimes(phases)
+
+	// giving queued requests time to drain after the last one is sent
+	drains := max(limit.Capacity/max(limit.NoOfRequests, 1), 1) + 1
+	horizon := length + limit.MaxDelay + time.Duration(drains)*limit.TimeDuration
+	if !s.run(sends, horizon) {
+		fmt.Println("some requests are still queued")
+	}
+
+	printTimeline(s.outcomes, *step, *verbose)
+	return nil
+}
+
+// function to get times at which requests are sent as per pattern and length of the pattern
+func sendTimes(phases []phase) ([]time.Duration, time.Duration) {
+	var sends []time.Duration
+	var offset time.Duration
+	for _, ph := range phases {
+		if ph.interval > 0 {
+			for at := time.Duration(0); at < ph.duration; at += ph.interval {
+				sends = append(sends, offset+at)
+			}
+		}
+		offset += ph.duration
+	}
+	return sends, offset
+}
+
+// replay of a traffic pattern against a strategy on a virtual clock
+// the clock of redis is moved from event to event, so no time is spent waiting
+type simulation struct {
+	mr *miniredis.Miniredis
+
+	// time at which the replay starts and time passed since
+	start time.Time
+	now   time.Duration
+
+	// strategy under test, stepped every interval as its timer would
+	limiter  limiter.Stepper
+	interval time.Duration
+	nextStep time.Duration
+
+	// whether throttled requests are held, for how long and how often they are retried
+	delay    bool
+	maxDelay time.Duration
+	retry    time.Duration
+
+	// cost of every request
+	cost int
+
+	// outcome of every request in order of sending
+	outcomes []*outcome
+
+	// held requests in order of arrival, only the first one polls the strategy
+	held []*held
+
+	// accepted requests waiting in the queue of the strategy
+	queued []*waiting
+}
+
+// request held in delay mode
+type held struct {
+	req *limiter.Request
+	out *outcome
+
+	// time of the last attempt and of the next one while it is the first held request
+	deadline time.Duration
+	next     time.Duration
+}
+
+// request accepted into the queue of the strategy
+type waiting struct {
+	ticket *limiter.Ticket
+	out    *outcome
+}
+
+// constructor to initialize replay of a limit, the clock of redis starts now
+func newSimulation(mr *miniredis.Miniredis, l limiter.Stepper, rateLimit *utils.RateLimit, cost int) *simulation {
+	s := &simulation{
+		mr:       mr,
+		start:    time.Now(),
+		limiter:  l,
+		interval: rateLimit.TimeDuration,
+		nextStep: rateLimit.TimeDuration,
+		delay:    rateLimit.Mode == "delay",
+		maxDelay: rateLimit.MaxDelay,
+		retry:    limiter.RetryInterval(rateLimit),
+		cost:     cost,
+	}
+	mr.SetTime(s.start)
+	return s
+}
+
+// function to run events in order of time till every request is decided, false if the horizon is reached first
+func (s *simulation) run(sends []time.Duration, horizon time.Duration) bool {
+	for len(sends) > 0 || len(s.held) > 0 || len(s.queued) > 0 {
+
+		// time of the next event
+		next := s.nextStep
+		if len(sends) > 0 {
+			next = min(next, sends[0])
+		}
+		for i, h := range s.held {
+			if i == 0 {
+				next = min(next, h.next)
+			} else {
+				next = min(next, h.deadline)
+			}
+		}
+		if next > horizon {
+			return false
+		}
+		s.advance(next)
+
+		// the timer of the strategy goes first, then held requests and new ones
+		if s.now == s.nextStep {
+			s.limiter.Step()
+			s.nextStep += s.interval
+			s.collect()
+		}
+		s.poll()
+		for len(sends) > 0 && sends[0] == s.now {
+			s.send()
+			sends = sends[1:]
+		}
+	}
+	return true
+}
+
+// function to move the clock of redis to a time of the replay
+func (s *simulation) advance(to time.Duration) {
+	s.mr.FastForward(to - s.now)
+	s.now = to
+	s.mr.SetTime(s.start.Add(to))
+}
+
+// function to send a request, held requests go first in delay mode
+func (s *simulation) send() {
+	out := &outcome{sent: s.now, served: -1}
+	s.outcomes = append(s.outcomes, out)
+	req := limiter.NewRequest(strconv.Itoa(len(s.outcomes)))
+	req.Cost = s.cost
+
+	if len(s.held) == 0 {
+		decision := s.limiter.Admit(context.Background(), req)
+		if decision.Allowed || !s.delay {
+			s.decide(out, decision)
+			return
+		}
+	}
+
+	// holding the request, the first one held retries after an interval as it was just tried
+	h := &held{req: req, out: out, deadline: s.now + s.maxDelay}
+	h.next = h.deadline
+	if len(s.held) == 0 {
+		h.next = s.now + min(s.retry, s.maxDelay)
+	}
+	s.held = append(s.held, h)
+	s.poll()
+}
+
+// function to retry held requests which are due
+func (s *simulation) poll() {
+
+	// the first held request polls till permitted or its deadline, then the next one takes its turn
+	for len(s.held) > 0 && s.held[0].next <= s.now {
+		h := s.held[0]
+		decision := s.limiter.Admit(context.Background(), h.req)
+		if !decision.Allowed && s.now < h.deadline {
+			h.next = s.now + min(s.retry, h.deadline-s.now)
+			break
+		}
+		s.decide(h.out, decision)
+		s.held = s.held[1:]
+		if len(s.held) > 0 {
+			s.held[0].next = s.now
+		}
+	}
+
+	// requests still waiting for their turn make a last attempt at their deadline
+	for i := 1; i < len(s.held); i++ {
+		h := s.held[i]
+		if h.deadline > s.now {
+			continue
+		}
+		s.decide(h.out, s.limiter.Admit(context.Background(), h.req))
+		s.held = append(s.held[:i], s.held[i+1:]...)
+		i--
+	}
+}
+
+// function to record the decision on a request
+func (s *simulation) decide(out *outcome, decision limiter.Decision) {
+	out.accepted = decision.Allowed
+	if !decision.Allowed {
+		return
+	}
+	if decision.Ticket == nil {
+		out.served = s.now
+		return
+	}
+	s.queued = append(s.queued, &waiting{ticket: decision.Ticket, out: out})
+	s.collect()
+}
+
+// function to serve queued requests admitted by the strategy
+func (s *simulation) collect() {
+	left := s.queued[:0]
+	for _, w := range s.queued {
+		select {
+		case <-w.ticket.Ready():
+			w.out.served = s.now
+		default:
+			left = append(left, w)
+		}
+	}
+	s.queued = left
+}
+
+// function to get a limit from a config file
+func configuredLimit(path string, resource string, tier string, method string) (*utils.RateLimit, error) {
+	cfg, err := utils.LoadConfiguration(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range cfg.Resources {
+		if res.Name != resource {
+			continue
+		}
+		limits := res.RateLimits
+		if tier != "" {
+			limits = res.Tiers[tier]
+		}
+		rateLimit, exists := limits[method]
+		if !exists {
+			return nil, fmt.Errorf("no limit for %s in resource %s", method, resource)
+		}
+		return rateLimit, nil
+	}
+	return nil, fmt.Errorf("no such resource %s", resource)
+}
+
+// function to parse traffic pattern like 50/s:2s,0/s:1s
+func parsePattern(pattern string) ([]phase, error) {
+	var phases []phase
+	for _, part := range strings.Split(pattern, ",") {
+		rate, length, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid phase %q, expected rate:duration", part)
+		}
+		duration, err := time.ParseDuration(length)
+		if err != nil {
+			return nil, fmt.Errorf("invalid phase %q: %w", part, err)
+		}
+
+		// a phase without requests, like 0/s:2s, lets the limit recover
+		ph := phase{duration: duration}
+		if n, _, _ := strings.Cut(rate, "/"); n == "0" {
+			phases = append(phases, ph)
+			continue
+		}
+		n, per, err := utils.SplitRate(rate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid phase %q: %w", part, err)
+		}
+		ph.interval = per / time.Duration(n)
+		phases = append(phases, ph)
+	}
+	return phases, nil
+}
+
+// function to print outcome of requests per step of time
+func printTimeline(outcomes []*outcome, step time.Duration, verbose bool) {
+	if verbose {
+		for i, out := range outcomes {
+			line := fmt.Sprintf("%8.3fs  #%-5d ", out.sent.Seconds(), i+1)
+			if out.accepted {
+				line += "accepted"
+				if out.served >= 0 {
+					line += fmt.Sprintf("  served after %s", (out.served - out.sent).Round(time.Millisecond))
+				}
+			} else {
+				line += "rejected"
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+
+	// counts per step
+	type counts struct{ sent, accepted, rejected, served int }
+	var timeline []counts
+	at := func(t time.Duration) *counts {
+		i := int(t / step)
+		for len(timeline) <= i {
+			timeline = append(timeline, counts{})
+		}
+		return &timeline[i]
+	}
+
+	var total counts
+	for _, out := range outcomes {
+		at(out.sent).sent++
+		total.sent++
+		if out.accepted {
+			at(out.sent).accepted++
+			total.accepted++
+		} else {
+			at(out.sent).rejected++
+			total.rejected++
+		}
+		if out.served >= 0 {
+			at(out.served).served++
+			total.served++
+		}
+	}
+
+	fmt.Printf("%10s %8s %8s %8s %8s\n", "TIME", "SENT", "ACCEPTED", "REJECTED", "SERVED")
+	for i, c := range timeline {
+		fmt.Printf("%10s %8d %8d %8d %8d\n", time.Duration(i)*step, c.sent, c.accepted, c.rejected, c.served)
+	}
+	fmt.Printf("%10s %8d %8d %8d %8d\n", "total", total.sent, total.accepted, total.rejected, total.served)
+}
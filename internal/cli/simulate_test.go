@@ -0,0 +1,101 @@
+// simulate_test.go
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    utils.RateLimit
+		pattern  string
+		accepted int
+		served   int
+
+		// latest time a request is served
+		last time.Duration
+	}{
+		{
+			name:     "hourly limit",
+			limit:    utils.RateLimit{Strategy: "TOKEN-BUCKET", Rate: "5/h", Capacity: 5},
+			pattern:  "1/m:3h",
+			accepted: 15, served: 15, last: 2*time.Hour + 4*time.Minute,
+		},
+		{
+			name:     "fixed window rejects",
+			limit:    utils.RateLimit{Strategy: "FIXED-WINDOW", Rate: "5/s"},
+			pattern:  "10/s:2s",
+			accepted: 10, served: 10, last: 1400 * time.Millisecond,
+		},
+		{
+			name:     "delayed till next window",
+			limit:    utils.RateLimit{Strategy: "FIXED-WINDOW", Rate: "5/s", Mode: "delay", MaxDelay: 2 * time.Second},
+			pattern:  "10/s:1s",
+			accepted: 10, served: 10, last: 1100 * time.Millisecond,
+		},
+		{
+			name:     "delay runs out",
+			limit:    utils.RateLimit{Strategy: "FIXED-WINDOW", Rate: "5/m", Mode: "delay", MaxDelay: 10 * time.Second},
+			pattern:  "10/s:1s",
+			accepted: 5, served: 5, last: 400 * time.Millisecond,
+		},
+		{
+			name:     "leaky bucket drips",
+			limit:    utils.RateLimit{Strategy: "LEAKY-BUCKET", Rate: "5/s", Capacity: 5},
+			pattern:  "20/s:1s",
+			accepted: 5, served: 5, last: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+			if err := limiter.Setup(context.Background(), rdb); err != nil {
+				t.Fatal(err)
+			}
+
+			limit := tt.limit
+			if err := limit.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			phases, err := parsePattern(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sends, length := sendTimes(phases)
+
+			s := newSimulation(mr, limiter.Steppers[limit.Strategy](rdb, &limit), &limit, 1)
+			defer s.limiter.Stop()
+			if !s.run(sends, length+limit.MaxDelay+2*limit.TimeDuration) {
+				t.Fatal("requests still queued")
+			}
+
+			var accepted, served int
+			var last time.Duration
+			for _, out := range s.outcomes {
+				if out.accepted {
+					accepted++
+				}
+				if out.served >= 0 {
+					served++
+					last = max(last, out.served)
+				}
+			}
+			if accepted != tt.accepted || served != tt.served {
+				t.Errorf("got %d accepted and %d served, want %d and %d", accepted, served, tt.accepted, tt.served)
+			}
+			if last != tt.last {
+				t.Errorf("got last served at %s, want %s", last, tt.last)
+			}
+		})
+	}
+}
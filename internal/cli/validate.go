@@ -0,0 +1,101 @@
+// validate.go
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/balancer"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/router"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// function to check a config file and print its resources
+func validate(args []string) error {
+	fs := newFlagSet("validate", "[config]")
+	config := fs.String("config", defaultConfig, "path of the config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		*config = fs.Arg(0)
+	}
+
+	// malformed settings are all reported while loading, a config that can't be read stops here
+	cfg, err := utils.LoadConfiguration(*config)
+	if cfg == nil {
+		return err
+	}
+
+	var problems []string
+	if err != nil {
+		problems = append(problems, strings.Split(err.Error(), "\n")...)
+	}
+	for i := range cfg.Resources {
+		resource := &cfg.Resources[i]
+
+		// compiling the route
+		if _, err := router.NewRoute(resource, i, nil); err != nil {
+			problems = append(problems, fmt.Sprintf("resource %s: %v", resource.Name, err))
+		}
+
+		if _, exists := balancer.Balancers[resource.Balancer]; !exists {
+			problems = append(problems, fmt.Sprintf("resource %s: no such balancer %s", resource.Name, resource.Balancer))
+		}
+		for method, rateLimit := range resource.RateLimits {
+			if _, exists := limiter.Limiters[rateLimit.Strategy]; !exists {
+				problems = append(problems, fmt.Sprintf("resource %s: %s: no such strategy %s", resource.Name, method, rateLimit.Strategy))
+			}
+		}
+		for tier, rateLimits := range resource.Tiers {
+			for method, rateLimit := range rateLimits {
+				if _, exists := limiter.Limiters[rateLimit.Strategy]; !exists {
+					problems = append(problems, fmt.Sprintf("resource %s: tier %s: %s: no such strategy %s", resource.Name, tier, method, rateLimit.Strategy))
+				}
+			}
+		}
+		if resource.Streaming != nil && resource.Streaming.MessageLimit != nil {
+			if _, exists := limiter.Limiters[resource.Streaming.MessageLimit.Strategy]; !exists {
+				problems = append(problems, fmt.Sprintf("resource %s: streaming: message limit: no such strategy %s", resource.Name, resource.Streaming.MessageLimit.Strategy))
+			}
+		}
+	}
+	for _, domain := range cfg.Decision.Domains {
+		problems = append(problems, descriptorProblems(domain.Name, domain.Descriptors)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config %s\n  %s", *config, strings.Join(problems, "\n  "))
+	}
+
+	fmt.Printf("%s is valid, %d resources\n", *config, len(cfg.Resources))
+	for _, resource := range cfg.Resources {
+		fmt.Printf("  %-16s %s %s -> %d destinations\n", resource.Name, resource.Match.Type, resource.Match.Host+resource.Match.Path, len(resource.Destinations))
+
+		methods := make([]string, 0, len(resource.RateLimits))
+		for method := range resource.RateLimits {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			rateLimit := resource.RateLimits[method]
+			fmt.Printf("    %-7s %s %s\n", method, rateLimit.Strategy, rateLimit.Rate)
+		}
+	}
+	return nil
+}
+
+// function to check strategies of descriptors and their nested descriptors
+func descriptorProblems(domain string, descriptors []utils.Descriptor) []string {
+	var problems []string
+	for _, descriptor := range descriptors {
+		if rateLimit := descriptor.RateLimit; rateLimit != nil {
+			if _, exists := limiter.Limiters[rateLimit.Strategy]; !exists {
+				problems = append(problems, fmt.Sprintf("domain %s: %s: no such strategy %s", domain, descriptor.Key, rateLimit.Strategy))
+			}
+		}
+		problems = append(problems, descriptorProblems(domain, descriptor.Descriptors)...)
+	}
+	return problems
+}
@@ -0,0 +1,55 @@
+// validate_test.go
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProblems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+resources:
+  - name: ws
+    endpoint: /ws
+    destination_url: http://localhost:9000
+    balancer: ROUND-ROBIN
+    streaming:
+      max_concurrent: 2
+      message_limit:
+        strategy: NOPE
+        rate: 10/s
+    rate_limits:
+      GET: {strategy: FIXED-WINDOW, rate: 10/s}
+      POST: {strategy: FIXED-WINDOW, rate: 10/0s}
+    tiers:
+      gold:
+        GET: {strategy: SLIDING, rate: 10/s}
+decision:
+  domains:
+    - domain: d
+      descriptors:
+        - key: path
+          rate_limit: {strategy: BUCKET, rate: 1/s}
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = validate([]string{path})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, want := range []string{
+		"resource ws: streaming: message limit: no such strategy NOPE",
+		"resource ws: POST: invalid rate: 10/0s",
+		"resource ws: tier gold: GET: no such strategy SLIDING",
+		"domain d: path: no such strategy BUCKET",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing problem %q in:\n%v", want, err)
+		}
+	}
+}
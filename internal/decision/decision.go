@@ -0,0 +1,220 @@
+// decision.go
+package decision
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/metrics"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// shortest time an unused partition of a limit is kept
+const minPartitionIdle = 5 * time.Minute
+
+// entry of a descriptor sent by the caller
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// outcome of check of a single descriptor
+type Status struct {
+	// descriptor exceeded its limit
+	OverLimit bool
+
+	// limit applying to the descriptor, nil if descriptor has no limit
+	Limit *utils.RateLimit
+
+	// units left in the limit after the check, -1 if unknown
+	Remaining int
+}
+
+// limit of descriptors ending at a node
+type rule struct {
+	limit *utils.RateLimit
+
+	// limiter per distinct descriptor
+	partitions *limiter.Partitioned
+}
+
+// node of the tree of configured descriptors
+type node struct {
+	// path of the node as used in redis keys and metrics
+	path string
+
+	// mapping of key=value or key for any value -> nested descriptor
+	children map[string]*node
+
+	// limit of descriptors ending here, nil if none
+	rule *rule
+}
+
+// service deciding on descriptors of all domains
+type Service struct {
+	// mapping of domain -> root of its descriptors
+	domains map[string]*node
+}
+
+// constructor to initialize service of all configured domains, state of the limits is kept in rdb
+func NewService(cfg *utils.DecisionService, rdb *redis.Client) *Service {
+	s := &Service{domains: make(map[string]*node)}
+	for _, domain := range cfg.Domains {
+		root := &node{children: make(map[string]*node)}
+		addDescriptors(root, domain.Descriptors, rdb)
+		s.domains[domain.Name] = root
+	}
+	return s
+}
+
+// function to add configured descriptors below a node
+func addDescriptors(parent *node, descriptors []utils.Descriptor, rdb *redis.Client) {
+	for _, descriptor := range descriptors {
+		name := descriptor.Key
+		if descriptor.Value != "" {
+			name += "=" + descriptor.Value
+		}
+		child := &node{children: make(map[string]*node), path: name}
+		if parent.path != "" {
+			child.path = parent.path + "/" + name
+		}
+		if descriptor.RateLimit != nil {
+			child.rule = newRule(descriptor.RateLimit, rdb)
+		}
+		addDescriptors(child, descriptor.Descriptors, rdb)
+		parent.children[name] = child
+	}
+}
+
+// constructor to initialize rule of a rate limit
+func newRule(rateLimit *utils.RateLimit, rdb *redis.Client) *rule {
+	algo, exists := limiter.Limiters[rateLimit.Strategy]
+	if !exists {
+		log.Fatalf("no such strategy %s", rateLimit.Strategy)
+	}
+
+	// function to initialize limiter of a descriptor, keyed by its values in redis
+	factory := func(key string) limiter.Limiter {
+		limit := *rateLimit
+		if limit.ID != "" {
+			limit.ID += ":" + key
+		}
+		l := algo(rdb, &limit)
+		// holding throttled checks instead of rejecting, never in dry run
+		if limit.Mode == "delay" && !limit.DryRun {
+			l = limiter.NewDelayed(l, &limit)
+		}
+		return l
+	}
+
+	return &rule{
+		limit:      rateLimit,
+		partitions: limiter.NewPartitioned(factory, max(minPartitionIdle, 2*rateLimit.TimeDuration), rateLimit.MaxKeys),
+	}
+}
+
+// function to get partition of the values of a descriptor, length prefixed so values holding
+// the separator cannot share a partition with other values
+func partitionKey(values []string) string {
+	var sb strings.Builder
+	for _, value := range values {
+		sb.WriteString(strconv.Itoa(len(value)))
+		sb.WriteByte(':')
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
+
+// function to find node matching all entries of a descriptor, specific values before any value
+func (n *node) match(entries []Entry) *node {
+	for _, entry := range entries {
+		child, exists := n.children[entry.Key+"="+entry.Value]
+		if !exists {
+			child, exists = n.children[entry.Key]
+		}
+		if !exists {
+			return nil
+		}
+		n = child
+	}
+	return n
+}
+
+// function to check descriptors of a domain, each one consuming hits units of its limit
+// queued and delayed checks block till admitted, rejected or ctx is done
+func (s *Service) Check(ctx context.Context, domain string, descriptors [][]Entry, hits int) ([]Status, error) {
+	root, exists := s.domains[domain]
+	if !exists {
+		return nil, fmt.Errorf("no such domain %s", domain)
+	}
+
+	statuses := make([]Status, len(descriptors))
+	for i, entries := range descriptors {
+		statuses[i] = Status{Remaining: -1}
+
+		// descriptors without a limit are never over limit
+		n := root.match(entries)
+		if n == nil || n.rule == nil {
+			continue
+		}
+		statuses[i].Limit = n.rule.limit
+
+		// every distinct set of values has its own limit
+		values := make([]string, len(entries))
+		for j, entry := range entries {
+			values[j] = entry.Value
+		}
+		req := limiter.NewRequest(uuid.NewString())
+		req.Cost = max(hits, 1)
+
+		decision := n.rule.partitions.For(partitionKey(values)).Admit(ctx, req)
+		statuses[i].Remaining = decision.Remaining
+
+		// waiting till admitted by queueing strategies
+		allowed := decision.Allowed
+		if allowed && decision.Ticket != nil {
+			allowed = decision.Ticket.Wait(ctx) == nil
+		}
+
+		// caller gave up while the request was held, it was not throttled
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		switch {
+		case allowed:
+			metrics.Requests.WithLabelValues(domain, n.path, "allowed").Inc()
+		case n.rule.limit.DryRun:
+			// limit is only evaluated, not enforced
+			metrics.Requests.WithLabelValues(domain, n.path, "dry_run_throttled").Inc()
+		default:
+			metrics.Requests.WithLabelValues(domain, n.path, "throttled").Inc()
+			statuses[i].OverLimit = true
+		}
+	}
+	return statuses, nil
+}
+
+// function to stop limiters of all descriptors
+func (s *Service) Stop() {
+	for _, root := range s.domains {
+		root.stop()
+	}
+}
+
+// function to stop limiters of a node and its children
+func (n *node) stop() {
+	if n.rule != nil {
+		n.rule.partitions.Stop()
+	}
+	for _, child := range n.children {
+		child.stop()
+	}
+}
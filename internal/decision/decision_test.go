@@ -0,0 +1,103 @@
+// decision_test.go
+package decision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+)
+
+func TestNodeMatch(t *testing.T) {
+	root := &node{children: make(map[string]*node)}
+	addDescriptors(root, []utils.Descriptor{
+		{Key: "remote_address"},
+		{Key: "path", Value: "/login"},
+		{Key: "path", Descriptors: []utils.Descriptor{
+			{Key: "method", Value: "POST"},
+			{Key: "method"},
+		}},
+	}, nil)
+
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    string
+	}{
+		{name: "any value", entries: []Entry{{"remote_address", "10.0.0.1"}}, want: "remote_address"},
+		{name: "specific value first", entries: []Entry{{"path", "/login"}}, want: "path=/login"},
+		{name: "any value fallback", entries: []Entry{{"path", "/home"}}, want: "path"},
+		{name: "nested specific", entries: []Entry{{"path", "/home"}, {"method", "POST"}}, want: "path/method=POST"},
+		{name: "nested any", entries: []Entry{{"path", "/home"}, {"method", "GET"}}, want: "path/method"},
+		{name: "nested below specific", entries: []Entry{{"path", "/login"}, {"method", "POST"}}},
+		{name: "unknown key", entries: []Entry{{"user", "alice"}}},
+		{name: "too deep", entries: []Entry{{"remote_address", "10.0.0.1"}, {"path", "/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := root.match(tt.entries)
+			if tt.want == "" {
+				if n != nil {
+					t.Fatalf("got node %s, want none", n.path)
+				}
+				return
+			}
+			if n == nil {
+				t.Fatalf("got no node, want %s", tt.want)
+			}
+			if n.path != tt.want {
+				t.Errorf("got node %s, want %s", n.path, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+	}{
+		{name: "separator in value", a: []string{"a:b", "c"}, b: []string{"a", "b:c"}},
+		{name: "length prefix in value", a: []string{"1:a"}, b: []string{"1", "a"}},
+		{name: "empty values", a: []string{"", "x"}, b: []string{"x", ""}},
+		{name: "joined values", a: []string{"ab"}, b: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ka, kb := partitionKey(tt.a), partitionKey(tt.b); ka == kb {
+				t.Errorf("%q and %q share partition %s", tt.a, tt.b, ka)
+			}
+		})
+	}
+	if got := partitionKey([]string{"10.0.0.1", "/login"}); got != "8:10.0.0.16:/login" {
+		t.Errorf("got partition %s", got)
+	}
+}
+
+func TestEnvoyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		reqs     int
+		duration time.Duration
+		want     uint32
+		unit     rlsv3.RateLimitResponse_RateLimit_Unit
+	}{
+		{name: "per second", reqs: 10, duration: time.Second, want: 10, unit: rlsv3.RateLimitResponse_RateLimit_SECOND},
+		{name: "per minute", reqs: 100, duration: time.Minute, want: 100, unit: rlsv3.RateLimitResponse_RateLimit_MINUTE},
+		{name: "per day", reqs: 5000, duration: 24 * time.Hour, want: 5000, unit: rlsv3.RateLimitResponse_RateLimit_DAY},
+		{name: "hours to hour", reqs: 600, duration: 2 * time.Hour, want: 300, unit: rlsv3.RateLimitResponse_RateLimit_HOUR},
+		{name: "seconds to second", reqs: 50, duration: 5 * time.Second, want: 10, unit: rlsv3.RateLimitResponse_RateLimit_SECOND},
+		{name: "not a whole minute", reqs: 90, duration: 90 * time.Second, want: 1, unit: rlsv3.RateLimitResponse_RateLimit_SECOND},
+		{name: "60 minutes is an hour", reqs: 7, duration: 60 * time.Minute, want: 7, unit: rlsv3.RateLimitResponse_RateLimit_HOUR},
+		{name: "uneven", reqs: 7, duration: 3 * time.Second, want: 7, unit: rlsv3.RateLimitResponse_RateLimit_UNKNOWN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := envoyLimit(&utils.RateLimit{NoOfRequests: tt.reqs, TimeDuration: tt.duration})
+			if got.RequestsPerUnit != tt.want || got.Unit != tt.unit {
+				t.Errorf("got %d per %s, want %d per %s", got.RequestsPerUnit, got.Unit, tt.want, tt.unit)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// envoy.go
+package decision
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// units of envoy limits, largest first
+var envoyUnits = []struct {
+	unit     rlsv3.RateLimitResponse_RateLimit_Unit
+	duration time.Duration
+}{
+	{rlsv3.RateLimitResponse_RateLimit_DAY, 24 * time.Hour},
+	{rlsv3.RateLimitResponse_RateLimit_HOUR, time.Hour},
+	{rlsv3.RateLimitResponse_RateLimit_MINUTE, time.Minute},
+	{rlsv3.RateLimitResponse_RateLimit_SECOND, time.Second},
+}
+
+// server implementing envoy's ratelimit.v3 RateLimitService
+type envoyServer struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+	service *Service
+}
+
+// constructor to initialize grpc server answering envoy
+func NewGRPCServer(service *Service) *grpc.Server {
+	srv := grpc.NewServer()
+	rlsv3.RegisterRateLimitServiceServer(srv, &envoyServer{service: service})
+	return srv
+}
+
+// function to decide on descriptors sent by envoy
+func (es *envoyServer) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	descriptors := make([][]Entry, len(req.GetDescriptors()))
+	for i, descriptor := range req.GetDescriptors() {
+		for _, entry := range descriptor.GetEntries() {
+			descriptors[i] = append(descriptors[i], Entry{Key: entry.GetKey(), Value: entry.GetValue()})
+		}
+	}
+
+	statuses, err := es.service.Check(ctx, req.GetDomain(), descriptors, int(req.GetHitsAddend()))
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	res := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+	for _, st := range statuses {
+		ds := &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+		if st.OverLimit {
+			ds.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+			res.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+			// a whole period is the longest a caller waits for quota
+			ds.DurationUntilReset = durationpb.New(st.Limit.TimeDuration)
+		}
+		if st.Limit != nil {
+			ds.CurrentLimit = envoyLimit(st.Limit)
+		}
+		if st.Remaining >= 0 {
+			ds.LimitRemaining = uint32(st.Remaining)
+		}
+		res.Statuses = append(res.Statuses, ds)
+	}
+	return res, nil
+}
+
+// function to express a rate limit in requests per envoy unit, unknown unit if it does not fit one
+func envoyLimit(rateLimit *utils.RateLimit) *rlsv3.RateLimitResponse_RateLimit {
+	for _, u := range envoyUnits {
+		if rateLimit.TimeDuration%u.duration != 0 {
+			continue
+		}
+		periods := int(rateLimit.TimeDuration / u.duration)
+		if rateLimit.NoOfRequests%periods == 0 {
+			return &rlsv3.RateLimitResponse_RateLimit{
+				RequestsPerUnit: uint32(rateLimit.NoOfRequests / periods),
+				Unit:            u.unit,
+			}
+		}
+	}
+	return &rlsv3.RateLimitResponse_RateLimit{
+		RequestsPerUnit: uint32(rateLimit.NoOfRequests),
+		Unit:            rlsv3.RateLimitResponse_RateLimit_UNKNOWN,
+	}
+}
@@ -0,0 +1,96 @@
+// http.go
+package decision
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// body of a check, same shape as envoy's RateLimitRequest
+type checkRequest struct {
+	Domain      string `json:"domain"`
+	Descriptors []struct {
+		Entries []Entry `json:"entries"`
+	} `json:"descriptors"`
+	HitsAddend int `json:"hits_addend"`
+}
+
+// limit of a descriptor as reported by the check api
+type checkLimit struct {
+	Strategy string `json:"strategy"`
+	Rate     string `json:"rate"`
+}
+
+// outcome of a single descriptor as reported by the check api
+type checkStatus struct {
+	Code       string      `json:"code"`
+	Limit      *checkLimit `json:"limit,omitempty"`
+	Remaining  *int        `json:"remaining,omitempty"`
+	RetryAfter float64     `json:"retry_after,omitempty"`
+}
+
+// response of a check
+type checkResponse struct {
+	OverallCode string        `json:"overall_code"`
+	Statuses    []checkStatus `json:"statuses"`
+}
+
+// function to initialize router for the check api
+func NewHTTPHandler(service *Service) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /check", checkHandler(service))
+	return mux
+}
+
+// function to decide on descriptors of a json check, over limit checks get 429
+func checkHandler(service *Service) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req checkRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body: " + err.Error()})
+			return
+		}
+
+		descriptors := make([][]Entry, len(req.Descriptors))
+		for i, descriptor := range req.Descriptors {
+			descriptors[i] = descriptor.Entries
+		}
+
+		statuses, err := service.Check(r.Context(), req.Domain, descriptors, req.HitsAddend)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		res := checkResponse{OverallCode: "OK", Statuses: []checkStatus{}}
+		for _, st := range statuses {
+			cs := checkStatus{Code: "OK"}
+			if st.OverLimit {
+				cs.Code = "OVER_LIMIT"
+				res.OverallCode = "OVER_LIMIT"
+				// a whole period is the longest a caller waits for quota
+				cs.RetryAfter = st.Limit.TimeDuration.Seconds()
+			}
+			if st.Limit != nil {
+				cs.Limit = &checkLimit{Strategy: st.Limit.Strategy, Rate: st.Limit.Rate}
+			}
+			if st.Remaining >= 0 {
+				cs.Remaining = &st.Remaining
+			}
+			res.Statuses = append(res.Statuses, cs)
+		}
+
+		code := http.StatusOK
+		if res.OverallCode == "OVER_LIMIT" {
+			code = http.StatusTooManyRequests
+		}
+		writeJSON(w, code, res)
+	}
+}
+
+// function to write json response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
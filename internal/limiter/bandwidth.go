@@ -0,0 +1,66 @@
+// bandwidth.go
+package limiter
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type Bandwidth struct {
+	// redis client holding the bucket
+	rdb *redis.Client
+
+	// key to track bytes in bucket
+	key string
+
+	// bytes refilled per second
+	rate int
+
+	// bucket capacity in bytes
+	burst int
+}
+
+// constructor to initialize bandwidth limit
+func NewBandwidth(rdb *redis.Client, key string, rate int, burst int) *Bandwidth {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &Bandwidth{
+		rdb:   rdb,
+		key:   key,
+		rate:  rate,
+		burst: burst,
+	}
+}
+
+// function to get largest no of bytes that can be taken at once
+func (bw *Bandwidth) Burst() int {
+	return bw.burst
+}
+
+// function to wait till bytes can be transferred
+func (bw *Bandwidth) Wait(ctx context.Context, bytes int) error {
+	for {
+		wait, err := Scripts["BANDWIDTH"].Run(ctx, bw.rdb, []string{bw.key}, "take", bw.rate, bw.burst, bytes).Int()
+		if err != nil {
+			// not throttling if redis is unreachable, bytes go through unlimited
+			slog.ErrorContext(ctx, "Error running script, not limiting bandwidth", "key", bw.key, "error", err)
+			return ctx.Err()
+		}
+		if wait == 0 {
+			return nil
+		}
+
+		// sleeping till enough bytes are refilled
+		timer := time.NewTimer(time.Duration(wait) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
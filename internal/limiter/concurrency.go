@@ -0,0 +1,74 @@
+// concurrency.go
+package limiter
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// time after which a slot is freed unless refreshed
+const concurrencyTTL = 30 * time.Second
+
+type Concurrency struct {
+	// redis client holding the slots
+	rdb *redis.Client
+
+	// key to track live slots
+	key string
+
+	// no of slots allowed at once
+	max int
+}
+
+// constructor to initialize concurrency limit
+func NewConcurrency(rdb *redis.Client, key string, max int) *Concurrency {
+	return &Concurrency{
+		rdb: rdb,
+		key: key,
+		max: max,
+	}
+}
+
+// function to acquire a slot, returned function releases it
+func (c *Concurrency) Acquire(id string) (func(), bool) {
+	res, err := Scripts["CONCURRENCY"].Run(context.Background(), c.rdb, []string{c.key}, "take", id, c.max, concurrencyTTL.Milliseconds()).Int()
+	if err != nil {
+		log.Println("Error:", err)
+		return nil, false
+	}
+	if res != 1 {
+		return nil, false
+	}
+
+	// keeping the slot alive till released
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.refresh(ctx, id)
+
+	return func() {
+		cancel()
+		Scripts["CONCURRENCY"].Run(context.Background(), c.rdb, []string{c.key}, "release", id)
+	}, true
+}
+
+// function to extend expiry of slot periodically
+func (c *Concurrency) refresh(ctx context.Context, id string) {
+
+	// initialize ticker to tick well before expiry
+	ticker := time.NewTicker(concurrencyTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+			Scripts["CONCURRENCY"].Run(ctx, c.rdb, []string{c.key}, "refresh", id, concurrencyTTL.Milliseconds())
+
+		// returning from function if slot is released
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// delay.go
+package limiter
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// bounds of the time between two attempts of a delayed request
+const (
+	minRetryInterval = 5 * time.Millisecond
+	maxRetryInterval = time.Second
+)
+
+// limiter holding throttled requests till a slot is free instead of rejecting them
+// held requests queue locally and only the oldest one polls the strategy, so they are
+// admitted in order of arrival and redis gets one attempt per retry whatever the no held
+type Delayed struct {
+	Limiter
+
+	// longest time a request is held
+	maxDelay time.Duration
+
+	// time between two attempts, average spacing of permitted requests
+	retry time.Duration
+
+	// turn of the held request polling the strategy, blocked senders are served in order
+	turn chan struct{}
+
+	// no of requests held
+	waiting atomic.Int64
+}
+
+// constructor to initialize delayed limiter around a strategy
+func NewDelayed(algo Limiter, rateLimit *utils.RateLimit) Limiter {
+	return &Delayed{
+		Limiter:  algo,
+		maxDelay: rateLimit.MaxDelay,
+		retry:    RetryInterval(rateLimit),
+		turn:     make(chan struct{}, 1),
+	}
+}
+
+// function to get time between two attempts of a delayed request, average spacing of permitted requests
+func RetryInterval(rateLimit *utils.RateLimit) time.Duration {
+	retry := rateLimit.TimeDuration / time.Duration(max(rateLimit.NoOfRequests, 1))
+	return min(max(retry, minRetryInterval), maxRetryInterval)
+}
+
+// function to retry admitting request till permitted, deadline or cancellation of context
+func (d *Delayed) Admit(ctx context.Context, req *Request) Decision {
+	deadline := time.Now().Add(d.maxDelay)
+
+	// trying right away unless older requests are held, they go first
+	if d.waiting.Load() == 0 {
+		decision := d.Limiter.Admit(ctx, req)
+		if decision.Allowed {
+			return decision
+		}
+	}
+	d.waiting.Add(1)
+	defer d.waiting.Add(-1)
+
+	// span of the delay
+	_, span := tracer.Start(ctx, "delay")
+	defer span.End()
+
+	// waiting for the turn behind older requests, last attempt is made at the deadline
+	timer := time.NewTimer(time.Until(deadline))
+	select {
+	case d.turn <- struct{}{}:
+		timer.Stop()
+	case <-timer.C:
+		return d.Limiter.Admit(ctx, req)
+
+	// giving up if client disconnects
+	case <-ctx.Done():
+		timer.Stop()
+		return deny(-1)
+	}
+	defer func() { <-d.turn }()
+
+	return d.poll(ctx, req, deadline)
+}
+
+// function to attempt admitting the oldest held request every retry interval
+func (d *Delayed) poll(ctx context.Context, req *Request, deadline time.Time) Decision {
+	for {
+		decision := d.Limiter.Admit(ctx, req)
+		if decision.Allowed {
+			return decision
+		}
+
+		// giving up once deadline is reached, last attempt is made at the deadline
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return decision
+		}
+		wait := min(d.retry, remaining)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+
+		// giving up if client disconnects
+		case <-ctx.Done():
+			timer.Stop()
+			return decision
+		}
+	}
+}
@@ -3,16 +3,18 @@ package limiter
 
 import (
 	"context"
-	"log"
-	"net/http/httputil"
+	"log/slog"
 	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type FixedWindow struct {
 
+	// redis client holding state of the limiter
+	rdb *redis.Client
+
 	// key to track requests in current window
 	key string
 
@@ -25,27 +27,29 @@ type FixedWindow struct {
 	// context for closure
 	ctx    context.Context
 	cancel context.CancelFunc
-
-	// corresponding proxy
-	proxy *httputil.ReverseProxy
 }
 
 // constructor to initialize window
-func NewFixedWindow(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter {
+func NewFixedWindow(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter {
+	fw := newFixedWindow(rdb, rateLimit)
+
+	// starting the resetting of window as a go routine once it is initalized
+	go fw.reset()
+
+	return fw
+}
+
+// function to initialize window without resetting it
+func newFixedWindow(rdb *redis.Client, rateLimit *utils.RateLimit) *FixedWindow {
 	ctx, cancel := context.WithCancel(context.Background())
-	fw := &FixedWindow{
-		key:          uuid.NewString(),
+	return &FixedWindow{
+		rdb:          rdb,
+		key:          limiterKey(rateLimit),
 		ctx:          ctx,
 		cancel:       cancel,
-		proxy:        proxy,
 		noOfRequests: rateLimit.NoOfRequests,
 		interval:     rateLimit.TimeDuration,
 	}
-
-	// starting the resetting of window as a go routine once it is initalized
-	go fw.reset()
-
-	return fw
 }
 
 // core functionality of the algorithm the resetting of window
@@ -60,8 +64,7 @@ func (fw *FixedWindow) reset() {
 
 		// refill as per rate
 		case <-ticker.C:
-			// reset current requests in window to 0
-			Scripts["FIXED-WINDOW"].Run(fw.ctx, Rdb, []string{fw.key}, "core")
+			fw.Step()
 
 		// returning from function if context is cancelled
 		case <-fw.ctx.Done():
@@ -71,20 +74,24 @@ func (fw *FixedWindow) reset() {
 
 }
 
-// function to increment requests in window and process the request
-func (fw *FixedWindow) AddRequest(req *Request) bool {
+// function to reset the window once, as done every interval
+func (fw *FixedWindow) Step() {
+	// reset current requests in window to 0
+	Scripts["FIXED-WINDOW"].Run(fw.ctx, fw.rdb, []string{fw.key}, "core", millis(fw.interval))
+}
+
+// function to increment requests in window if request is permitted
+func (fw *FixedWindow) Admit(ctx context.Context, req *Request) Decision {
 	// check if request is permitted
-	res, err := Scripts["FIXED-WINDOW"].Run(fw.ctx, Rdb, []string{fw.key}, "take", fw.noOfRequests).Int()
+	res, err := Scripts["FIXED-WINDOW"].Run(traced(fw.ctx, ctx), fw.rdb, []string{fw.key}, "take", fw.noOfRequests, req.Cost, millis(fw.interval)).Int64Slice()
 	if err != nil {
-		log.Println("Error:", err)
-		return false
+		slog.ErrorContext(ctx, "Error running script", "error", err)
+		return deny(-1)
 	}
-	if res == 1 {
-		go ServeReq(fw.proxy, req, nil)
-		return true
-	} else {
-		return false
+	if res[0] == 1 {
+		return Decision{Allowed: true, Remaining: int(res[1])}
 	}
+	return deny(int(res[1]))
 }
 
 // function to stop the algorithm
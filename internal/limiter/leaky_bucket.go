@@ -4,19 +4,35 @@ package limiter
 import (
 	"context"
 	"log"
-	"net/http/httputil"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
+// queued request of a leaky bucket
+type queued struct {
+	ticket *Ticket
+
+	// position of the last slot taken by the request
+	position int64
+}
+
 type LeakyBucket struct {
+	// redis client holding state of the limiter
+	rdb *redis.Client
+
 	// key to track bucket
 	key string
 
-	// temperoray mapping of id -> request
-	reqs map[string]*Request
+	// temperoray mapping of id -> ticket of queued request of this replica
+	tickets map[string]*queued
+	mu      sync.Mutex
+
+	// position of head of the queue as last dripped
+	head int64
 
 	// queue capacity
 	capacity int
@@ -27,31 +43,39 @@ type LeakyBucket struct {
 	// time duration unit
 	interval time.Duration
 
+	// time after which an untouched queue is dropped
+	ttl time.Duration
+
 	// context for closure
 	ctx    context.Context
 	cancel context.CancelFunc
-
-	// corresponding proxy
-	proxy *httputil.ReverseProxy
 }
 
 // constructor to initialize leaky bucket
-func NewLeakyBucket(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter {
+func NewLeakyBucket(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter {
+	lb := newLeakyBucket(rdb, rateLimit)
+
+	// starting the dripping of bucket as a go routine once it is initalized
+	go lb.drip()
+
+	return lb
+}
+
+// function to initialize leaky bucket without dripping it
+func newLeakyBucket(rdb *redis.Client, rateLimit *utils.RateLimit) *LeakyBucket {
 	ctx, cancel := context.WithCancel(context.Background())
 	lb := &LeakyBucket{
-		key:          uuid.NewString(),
-		reqs:         make(map[string]*Request),
+		rdb:          rdb,
+		key:          limiterKey(rateLimit),
+		tickets:      make(map[string]*queued),
 		capacity:     rateLimit.Capacity,
 		ctx:          ctx,
 		cancel:       cancel,
-		proxy:        proxy,
 		noOfRequests: rateLimit.NoOfRequests,
 		interval:     rateLimit.TimeDuration,
 	}
-
-	// starting the dripping of bucket as a go routine once it is initalized
-	go lb.drip()
-
+	drips := (lb.capacity + lb.noOfRequests - 1) / max(lb.noOfRequests, 1)
+	lb.ttl = time.Duration(drips+2) * lb.interval
 	return lb
 }
 
@@ -62,35 +86,12 @@ func (lb *LeakyBucket) drip() {
 	ticker := time.NewTicker(lb.interval)
 	defer ticker.Stop()
 
-	// following worker pool pattern
-	limit := min(100, lb.noOfRequests)
-	worker := make(chan struct{}, limit)
-
 	for {
 		select {
 
 		// dripping as per rate
 		case <-ticker.C:
-			// get all dripped requests
-			dripped, err := Scripts["LEAKY-BUCKET"].Run(lb.ctx, Rdb, []string{lb.key}, "core", lb.noOfRequests).StringSlice()
-
-			if err != nil {
-				log.Printf("Error :%v", err)
-			}
-			// start serving all dripped request
-			for _, id := range dripped {
-
-				// acquire slot
-				worker <- struct{}{}
-				// serve request
-				req, exists := lb.reqs[id]
-				if !exists {
-					<-worker
-					continue
-				}
-				go ServeReq(lb.proxy, req, worker)
-				delete(lb.reqs, id)
-			}
+			lb.Step()
 
 		// returning from function if context is cancelled
 		case <-lb.ctx.Done():
@@ -100,21 +101,52 @@ func (lb *LeakyBucket) drip() {
 
 }
 
-// function to add request to queue
-func (lb *LeakyBucket) AddRequest(req *Request) bool {
+// function to drip the bucket once, as done every interval
+func (lb *LeakyBucket) Step() {
+	// get position of head once dripped by any replica
+	head, err := Scripts["LEAKY-BUCKET"].Run(lb.ctx, lb.rdb, []string{lb.key}, "core", lb.noOfRequests, millis(lb.interval), millis(lb.ttl)).Int64()
+	if err != nil {
+		log.Printf("Error :%v", err)
+		return
+	}
+
+	// admitting all dripped requests, or all of them if the queue was reset
+	lb.mu.Lock()
+	reset := head < lb.head
+	lb.head = head
+	for id, q := range lb.tickets {
+		if reset || q.position <= head {
+			q.ticket.admit()
+			delete(lb.tickets, id)
+		}
+	}
+	lb.mu.Unlock()
+}
+
+// function to add request to queue, request is admitted once its last slot is dripped
+func (lb *LeakyBucket) Admit(ctx context.Context, req *Request) Decision {
 
 	// adding the request to queue if space available
-	res, err := Scripts["LEAKY-BUCKET"].Run(lb.ctx, Rdb, []string{lb.key}, "take", req.ID, lb.capacity).Int()
+	res, err := Scripts["LEAKY-BUCKET"].Run(traced(lb.ctx, ctx), lb.rdb, []string{lb.key}, "take", lb.capacity, req.Cost, millis(lb.ttl)).Int64Slice()
 	if err != nil {
-		log.Println("Error:", err)
-		return false
+		slog.ErrorContext(ctx, "Error running script", "error", err)
+		return deny(-1)
 	}
-	if res == 1 {
-		lb.reqs[req.ID] = req
-		return true
+	if res[0] != 1 {
+		return deny(int(res[1]))
+	}
+
+	// registering ticket, admitted right away if dripped before being registered
+	ticket := newTicket()
+	lb.mu.Lock()
+	if res[2] <= lb.head {
+		ticket.admit()
 	} else {
-		return false
+		lb.tickets[req.ID] = &queued{ticket: ticket, position: res[2]}
 	}
+	lb.mu.Unlock()
+
+	return Decision{Allowed: true, Remaining: int(res[1]), Ticket: ticket}
 }
 
 // function to stop the algorithm
@@ -3,17 +3,17 @@ package limiter
 
 import (
 	"context"
-	"log"
-	"net/http"
-	"net/http/httputil"
+	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
 // limiter interface to support all common functions of a rate limiter
+// a limiter only decides admission, serving the request is left to the caller
 type Limiter interface {
-	AddRequest(*Request) bool
+	Admit(ctx context.Context, req *Request) Decision
 	Stop()
 }
 
@@ -22,92 +22,142 @@ type Request struct {
 	// request id
 	ID string
 
-	// actual http request
-	r *http.Request
-
-	// http response writer
-	w http.ResponseWriter
-
-	// context for closure
-	Ctx    context.Context
-	cancel context.CancelFunc
+	// no of units consumed by the request
+	Cost int
 }
 
 // constructor to initialize request
-func NewRequest(id string, w http.ResponseWriter, r *http.Request) *Request {
-	ctx, cancel := context.WithCancel(context.Background())
+func NewRequest(id string) *Request {
 	return &Request{
-		ID:     id,
-		r:      r,
-		w:      w,
-		Ctx:    ctx,
-		cancel: cancel,
+		ID:   id,
+		Cost: 1,
 	}
 }
 
-func ServeReq(proxy *httputil.ReverseProxy, req *Request, worker chan struct{}) {
+// outcome of admission of a request
+type Decision struct {
+	// request may proceed, once its ticket is admitted if queued
+	Allowed bool
 
-	// releasing the worker if present
-	defer func() {
-		if worker != nil {
-			<-worker
-		}
-	}()
+	// units left in the limit after the decision, -1 if unknown
+	Remaining int
 
-	// skipping if client disconnects
-	if req.r.Context().Err() != nil {
-		log.Println("Skipping request: client disconnected")
-		req.cancel()
-		return
-	}
+	// ticket of a queued request, nil if not queued
+	Ticket *Ticket
+}
 
-	log.Printf("Redirecting to %s\n", req.r.URL)
+// function to get decision denying a request
+func deny(remaining int) Decision {
+	return Decision{Remaining: remaining}
+}
 
-	// serving the request through proxy
-	proxy.ServeHTTP(req.w, req.r)
+// place of a request waiting in queue of a limiter
+type Ticket struct {
+	// closed once the request is admitted
+	ready chan struct{}
+}
 
-	// closing the request
-	req.cancel()
+// constructor to initialize ticket
+func newTicket() *Ticket {
+	return &Ticket{ready: make(chan struct{})}
 }
 
-// all limiters
-// alias for the common function
-type LimiterFunc func(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter
+// function to admit the queued request
+func (t *Ticket) admit() {
+	close(t.ready)
+}
+
+// function to block till the request is admitted or context is done
+func (t *Ticket) Wait(ctx context.Context) error {
+	// measuring time spent in queue
+	_, span := tracer.Start(ctx, "queue")
+	defer span.End()
+
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-var Limiters map[string]LimiterFunc
+// function to get channel closed once the request is admitted
+func (t *Ticket) Ready() <-chan struct{} {
+	return t.ready
+}
 
-// all lua scripts asper strategy
-var Scripts map[string]*redis.Script
+// function to get redis key of a limiter, random if limit has no id
+// limits with the same id share their keys across replicas, scripts run timed steps once per interval
+func limiterKey(rateLimit *utils.RateLimit) string {
+	if rateLimit.ID == "" {
+		return uuid.NewString()
+	}
+	return rateLimit.ID
+}
 
-// redis client
-var Rdb *redis.Client
+// suffixes of keys strategies keep next to the key of a limiter
+var keySuffixes = []string{"", ":slot", ":curr", ":prev", ":timeStamp"}
 
-// init function is required if any global var is declared
-func init() {
-	// initializing all limiters
-	Limiters = map[string]LimiterFunc{
+// function to delete state of a limit in redis, for limits no one else uses once stopped
+func Delete(ctx context.Context, rdb *redis.Client, id string) error {
+	return rdb.Del(ctx, Keys(id)...).Err()
+}
 
-		"LEAKY-BUCKET":       NewLeakyBucket,
-		"TOKEN-BUCKET":       NewTokenBucket,
-		"FIXED-WINDOW":       NewFixedWindow,
-		"SLIDING-WINDOW":     NewSlidingWindow,
-		"SLIDING-WINDOW-LOG": NewSlidingWindowLog,
+// function to get all keys a limit may keep in redis
+func Keys(id string) []string {
+	keys := make([]string, len(keySuffixes))
+	for i, suffix := range keySuffixes {
+		keys[i] = id + suffix
 	}
+	return keys
+}
 
-	// initializing redis client
-	Rdb = utils.InitRedis()
+// function to get a duration in milliseconds as passed to scripts, at least 1
+func millis(d time.Duration) int64 {
+	return max(d.Milliseconds(), 1)
+}
 
-	// directory path for all scripts
-	dirPath := "internal/limiter/scripts/"
+// all limiters
+// alias for the common function
+type LimiterFunc func(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter
 
-	// initializing all scripts 
-	Scripts = map[string]*redis.Script{
+var Limiters = map[string]LimiterFunc{
 
-		"LEAKY-BUCKET":       utils.LoadScript(dirPath + "leaky_bucket.lua"),
-		"TOKEN-BUCKET":       utils.LoadScript(dirPath + "token_bucket.lua"),
-		"FIXED-WINDOW":       utils.LoadScript(dirPath + "fixed_window.lua"),
-		"SLIDING-WINDOW":     utils.LoadScript(dirPath + "sliding_window.lua"),
-		"SLIDING-WINDOW-LOG": utils.LoadScript(dirPath + "sliding_window_log.lua"),
-	}
+	"LEAKY-BUCKET":       NewLeakyBucket,
+	"TOKEN-BUCKET":       NewTokenBucket,
+	"FIXED-WINDOW":       NewFixedWindow,
+	"SLIDING-WINDOW":     NewSlidingWindow,
+	"SLIDING-WINDOW-LOG": NewSlidingWindowLog,
+}
+
+// limiter whose timed work is run by the caller, to replay traffic on a virtual clock
+type Stepper interface {
+	Limiter
 
+	// function to run the timed work of the strategy once, as done every interval
+	Step()
 }
+
+// all limiters without their timers
+type StepperFunc func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper
+
+var Steppers = map[string]StepperFunc{
+	"LEAKY-BUCKET": func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper {
+		return newLeakyBucket(rdb, rateLimit)
+	},
+	"TOKEN-BUCKET": func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper {
+		return newTokenBucket(rdb, rateLimit)
+	},
+	"FIXED-WINDOW": func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper {
+		return newFixedWindow(rdb, rateLimit)
+	},
+	"SLIDING-WINDOW": func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper {
+		return newSlidingWindow(rdb, rateLimit)
+	},
+	"SLIDING-WINDOW-LOG": func(rdb *redis.Client, rateLimit *utils.RateLimit) Stepper {
+		return newSlidingWindowLog(rdb, rateLimit)
+	},
+}
+
+// all lua scripts asper strategy, the same for every redis client
+var Scripts = NewScripts()
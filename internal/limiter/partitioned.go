@@ -0,0 +1,124 @@
+// partitioned.go
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter of a single partition with its last use
+type partition struct {
+	Limiter
+	key      string
+	lastUsed time.Time
+}
+
+// limiter keeping a separate limiter per client key
+type Partitioned struct {
+	// function to initialize limiter of a new partition
+	factory func(key string) Limiter
+
+	// mapping of client key -> partition in lru
+	partitions map[string]*list.Element
+	mu         sync.Mutex
+
+	// partitions, most recently used first
+	lru *list.List
+
+	// time after which an unused partition is dropped
+	idle time.Duration
+
+	// most partitions kept at once, least recently used one is dropped beyond it
+	// keys may come from clients, so their limiters and go routines must not grow forever
+	max int
+
+	// context for closure
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// constructor to initialize partitioned limiter
+func NewPartitioned(factory func(key string) Limiter, idle time.Duration, max int) *Partitioned {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Partitioned{
+		factory:    factory,
+		partitions: make(map[string]*list.Element),
+		lru:        list.New(),
+		idle:       idle,
+		max:        max,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	// starting the eviction of idle partitions as a go routine
+	go p.evict()
+
+	return p
+}
+
+// function to get limiter of a client key, created on first use
+// state of dropped partitions stays in redis till it expires, so a partition created again carries on
+func (p *Partitioned) For(key string) Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elem, exists := p.partitions[key]
+	if exists {
+		p.lru.MoveToFront(elem)
+	} else {
+		if p.max > 0 && p.lru.Len() >= p.max {
+			p.remove(p.lru.Back())
+		}
+		elem = p.lru.PushFront(&partition{Limiter: p.factory(key), key: key})
+		p.partitions[key] = elem
+	}
+	part := elem.Value.(*partition)
+	part.lastUsed = time.Now()
+	return part.Limiter
+}
+
+// function to stop limiter of a partition and drop it, lock must be held
+func (p *Partitioned) remove(elem *list.Element) {
+	part := elem.Value.(*partition)
+	part.Stop()
+	p.lru.Remove(elem)
+	delete(p.partitions, part.key)
+}
+
+// function to stop limiters of partitions unused for too long
+func (p *Partitioned) evict() {
+
+	// initialize ticker to tick every idle duration
+	ticker := time.NewTicker(p.idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ticker.C:
+			// least recently used partitions are at the back
+			p.mu.Lock()
+			for elem := p.lru.Back(); elem != nil && time.Since(elem.Value.(*partition).lastUsed) > p.idle; elem = p.lru.Back() {
+				p.remove(elem)
+			}
+			p.mu.Unlock()
+
+		// returning from function if context is cancelled
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// function to stop limiters of all partitions
+func (p *Partitioned) Stop() {
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for elem := p.lru.Back(); elem != nil; elem = p.lru.Back() {
+		p.remove(elem)
+	}
+}
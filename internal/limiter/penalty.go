@@ -0,0 +1,128 @@
+// penalty.go
+package limiter
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// longest time a ban is answered from local cache, so bans lifted in redis are honored soon
+const banCacheTTL = 5 * time.Second
+
+// locally known ban of a client
+type cachedBan struct {
+	// end of ban
+	until time.Time
+
+	// end of validity of the cache entry
+	expires time.Time
+}
+
+// suffixes of keys the penalty box keeps per client
+var penaltySuffixes = []string{":ban", ":strikes", ":offenses"}
+
+// function to get all keys the penalty box may keep in redis for a client, key is prefix:client
+func PenaltyKeys(key string) []string {
+	keys := make([]string, len(penaltySuffixes))
+	for i, suffix := range penaltySuffixes {
+		keys[i] = key + suffix
+	}
+	return keys
+}
+
+type Penalty struct {
+	// redis client holding strikes and bans shared by all replicas
+	rdb *redis.Client
+
+	// prefix of keys tracking clients
+	key string
+
+	// no of throttles within window leading to a ban
+	threshold int
+	window    time.Duration
+
+	// first ban, doubled on every repeated offense till max ban
+	ban    time.Duration
+	maxBan time.Duration
+
+	// time for which offenses are remembered
+	memory time.Duration
+
+	// locally known bans, client -> ban
+	banned map[string]cachedBan
+	mu     sync.Mutex
+}
+
+// constructor to initialize penalty box
+func NewPenalty(rdb *redis.Client, key string, cfg *utils.Penalty) *Penalty {
+	return &Penalty{
+		rdb:       rdb,
+		key:       key,
+		threshold: cfg.Threshold,
+		window:    cfg.Window,
+		ban:       cfg.Ban,
+		maxBan:    cfg.MaxBan,
+		memory:    cfg.Memory,
+		banned:    make(map[string]cachedBan),
+	}
+}
+
+// function to get remaining ban of a client, 0 if not banned
+func (p *Penalty) Banned(client string) time.Duration {
+
+	// answering from local cache to spare redis while client is banned
+	p.mu.Lock()
+	cached, exists := p.banned[client]
+	if exists && time.Now().After(cached.expires) {
+		delete(p.banned, client)
+		exists = false
+	}
+	p.mu.Unlock()
+	if exists {
+		return time.Until(cached.until)
+	}
+
+	res, err := Scripts["PENALTY"].Run(context.Background(), p.rdb, []string{p.key + ":" + client}, "check").Int64()
+	if err != nil {
+		log.Println("Error:", err)
+		return 0
+	}
+	if res > 0 {
+		p.remember(client, time.Duration(res)*time.Millisecond)
+	}
+	return time.Duration(res) * time.Millisecond
+}
+
+// function to record a throttle of a client, returns duration of ban if banned
+func (p *Penalty) Strike(client string) time.Duration {
+	res, err := Scripts["PENALTY"].Run(context.Background(), p.rdb, []string{p.key + ":" + client}, "strike",
+		p.threshold, p.window.Milliseconds(), p.ban.Milliseconds(), p.maxBan.Milliseconds(), p.memory.Milliseconds()).Int64()
+	if err != nil {
+		log.Println("Error:", err)
+		return 0
+	}
+	if res > 0 {
+		p.remember(client, time.Duration(res)*time.Millisecond)
+	}
+	return time.Duration(res) * time.Millisecond
+}
+
+// function to cache ban of a client
+func (p *Penalty) remember(client string, ban time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// dropping expired bans so cache does not grow forever
+	now := time.Now()
+	for c, cached := range p.banned {
+		if now.After(cached.expires) {
+			delete(p.banned, c)
+		}
+	}
+	p.banned[client] = cachedBan{until: now.Add(ban), expires: now.Add(min(ban, banCacheTTL))}
+}
@@ -0,0 +1,56 @@
+// scripts.go
+package limiter
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lua scripts compiled into the binary
+//
+//go:embed scripts/*.lua
+var scriptFiles embed.FS
+
+// mapping of script name -> file
+var scriptNames = map[string]string{
+
+	"LEAKY-BUCKET":       "leaky_bucket.lua",
+	"TOKEN-BUCKET":       "token_bucket.lua",
+	"FIXED-WINDOW":       "fixed_window.lua",
+	"SLIDING-WINDOW":     "sliding_window.lua",
+	"SLIDING-WINDOW-LOG": "sliding_window_log.lua",
+	"CONCURRENCY":        "concurrency.lua",
+	"BANDWIDTH":          "bandwidth.lua",
+	"PENALTY":            "penalty.lua",
+}
+
+// function to initialize all scripts from the binary
+func NewScripts() map[string]*redis.Script {
+	scripts := make(map[string]*redis.Script, len(scriptNames))
+	for name, file := range scriptNames {
+		data, err := scriptFiles.ReadFile("scripts/" + file)
+		if err != nil {
+			// only possible if the embed pattern is broken
+			panic(fmt.Sprintf("missing script %s: %v", file, err))
+		}
+		scripts[name] = redis.NewScript(string(data))
+	}
+	return scripts
+}
+
+// function to prepare a redis client for limiters, any no of clients can be set up
+// scripts are preloaded so first calls do not fall back to sending the whole script
+func Setup(ctx context.Context, rdb *redis.Client) error {
+	// tracing script calls made on behalf of requests
+	rdb.AddHook(newScriptTracing(Scripts))
+
+	for name, script := range Scripts {
+		if err := script.Load(ctx, rdb).Err(); err != nil {
+			return fmt.Errorf("unable to load script %s: %w", name, err)
+		}
+	}
+	return nil
+}
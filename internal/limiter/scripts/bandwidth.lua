@@ -0,0 +1,40 @@
+-- bandwidth.lua
+
+-- function to take bytes from the bucket refilled continuously as per rate
+-- returns 0 if permitted else milliseconds to wait before retrying
+local function take(key, rate, burst, bytes)
+    local time_data = redis.call("TIME")
+    -- converting to milliseconds
+    local now = time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+
+    local data = redis.call("HMGET", key, "tokens", "timeStamp")
+    local tokens = tonumber(data[1]) or burst
+    local timeStamp = tonumber(data[2]) or now
+
+    -- refilling for the elapsed time
+    tokens = math.min(burst, tokens + (now - timeStamp) * rate / 1000)
+
+    local wait = 0
+    if tokens >= bytes then
+        tokens = tokens - bytes
+    else
+        wait = math.ceil((bytes - tokens) * 1000 / rate)
+    end
+
+    redis.call("HSET", key, "tokens", tokens, "timeStamp", now)
+    -- bucket is full again after this long so state can be dropped
+    redis.call("PEXPIRE", key, math.ceil(burst * 1000 / rate) + 1000)
+
+    return wait
+end
+
+local command = ARGV[1]
+local key = KEYS[1]
+if command == "take" then
+    local rate = tonumber(ARGV[2])
+    local burst = tonumber(ARGV[3])
+    local bytes = tonumber(ARGV[4])
+    return take(key, rate, burst, bytes)
+else
+    return redis.error_reply("Invalid command")
+end
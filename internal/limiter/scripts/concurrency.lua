@@ -0,0 +1,51 @@
+-- concurrency.lua
+
+-- function to get current time in milliseconds
+local function now_ms()
+    local time_data = redis.call("TIME")
+    return time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+end
+
+-- function to register a stream if below the limit
+-- every stream expires unless refreshed so crashed replicas do not leak slots
+local function take(key, id, max, ttl)
+    local now = now_ms()
+    redis.call("ZREMRANGEBYSCORE", key, "-inf", now)
+    if redis.call("ZCARD", key) < max then
+        redis.call("ZADD", key, now + ttl, id)
+        redis.call("PEXPIRE", key, ttl)
+        return 1
+    else
+        return 0
+    end
+end
+
+-- function to extend expiry of a live stream
+local function refresh(key, id, ttl)
+    redis.call("ZADD", key, "XX", now_ms() + ttl, id)
+    redis.call("PEXPIRE", key, ttl)
+    return 1
+end
+
+-- function to unregister a stream
+local function release(key, id)
+    return redis.call("ZREM", key, id)
+end
+
+local command = ARGV[1]
+local key = KEYS[1]
+if command == "take" then
+    local id = tostring(ARGV[2])
+    local max = tonumber(ARGV[3])
+    local ttl = tonumber(ARGV[4])
+    return take(key, id, max, ttl)
+elseif command == "refresh" then
+    local id = tostring(ARGV[2])
+    local ttl = tonumber(ARGV[3])
+    return refresh(key, id, ttl)
+elseif command == "release" then
+    local id = tostring(ARGV[2])
+    return release(key, id)
+else
+    return redis.error_reply("Invalid command")
+end
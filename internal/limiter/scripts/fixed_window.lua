@@ -1,19 +1,35 @@
 -- fixed_window.lua
 
--- function to reset request in new window to 0
-local function reset_reqs(key)
-    redis.call("SET", key, 0)
+-- function to get no of intervals since the core last ran on any replica, 0 if it already ran in this one
+-- every replica runs its own timer on the shared window, so only the first one per interval does the work
+local function passed_intervals(key, interval, ttl)
+    local time_data = redis.call("TIME")
+    -- converting to milliseconds
+    local now = time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+    local slot = math.floor(now / interval)
+    local last = tonumber(redis.call("GET", key .. ":slot") or slot - 1)
+    redis.call("SET", key .. ":slot", slot, "PX", ttl)
+    return slot - last
+end
+
+-- function to reset request in new window to 0, missing window has no requests
+local function reset_reqs(key, interval)
+    if passed_intervals(key, interval, 2 * interval) > 0 then
+        redis.call("DEL", key)
+    end
     return 1
 end
 
--- function to allow request if still space in window
-local function take(key, no_of_reqs)
+-- function to allow request if still space in window, returns whether allowed and space left
+-- window expires on its own if no replica resets it
+local function take(key, no_of_reqs, cost, interval)
     local reqs = tonumber(redis.call("GET", key) or 0)
-    if reqs < no_of_reqs then
-        redis.call("INCR", key)
-        return 1
+    if reqs + cost <= no_of_reqs then
+        redis.call("INCRBY", key, cost)
+        redis.call("PEXPIRE", key, 2 * interval)
+        return {1, no_of_reqs - reqs - cost}
     else
-        return 0
+        return {0, math.max(0, no_of_reqs - reqs)}
     end
 end
 
@@ -21,9 +37,12 @@ local command = ARGV[1]
 local key = KEYS[1]
 if command == "take" then
     local no_of_reqs = tonumber(ARGV[2])
-    return take(key, no_of_reqs)
+    local cost = tonumber(ARGV[3] or 1)
+    local interval = tonumber(ARGV[4])
+    return take(key, no_of_reqs, cost, interval)
 elseif command == "core" then
-    return reset_reqs(key)
+    local interval = tonumber(ARGV[2])
+    return reset_reqs(key, interval)
 else
     return redis.error_reply("Invalid command")
 end
@@ -1,32 +1,67 @@
 -- leaky_bucket.lua
+-- the queue is kept as positions, tail is the no of slots ever taken and head the no of slots dripped
+-- a request is dripped once head reaches its last slot, so replicas only admit their own requests
 
--- function to get specified no of oldest requests
-local function drip_reqs(key, no_of_reqs)
-    local res = redis.call("LRANGE", key, 0, no_of_reqs - 1)
-    redis.call("LTRIM", key, no_of_reqs, -1)
-    return res
+-- function to get no of intervals since the core last ran on any replica, 0 if it already ran in this one
+-- every replica runs its own timer on the shared bucket, so only the first one per interval does the work
+local function passed_intervals(key, interval, ttl)
+    local time_data = redis.call("TIME")
+    -- converting to milliseconds
+    local now = time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+    local slot = math.floor(now / interval)
+    local last = tonumber(redis.call("GET", key .. ":slot") or slot - 1)
+    redis.call("SET", key .. ":slot", slot, "PX", ttl)
+    return slot - last
+end
+
+-- function to drip specified no of oldest slots per interval passed, returns position of head
+local function drip_reqs(key, no_of_reqs, interval, ttl)
+    local data = redis.call("HMGET", key, "head", "tail")
+    local head = tonumber(data[1]) or 0
+    local tail = tonumber(data[2]) or 0
+
+    local passed = passed_intervals(key, interval, ttl)
+    if passed > 0 and head < tail then
+        head = math.min(tail, head + no_of_reqs * passed)
+        redis.call("HSET", key, "head", head)
+    end
+    if tail > 0 then
+        redis.call("PEXPIRE", key, ttl)
+    end
+    return head
 end
 
 -- function to permit request if bucket is not full
-local function take(key, id, capacity)
-    local reqs = redis.call("LLEN", key)
-    if reqs < capacity then
-        redis.call("LPUSH", key, id)
-        return 1
+-- request takes one slot per unit of cost and drips once all of them drip
+-- returns whether permitted, slots left and position of the last slot taken
+local function take(key, capacity, cost, ttl)
+    local data = redis.call("HMGET", key, "head", "tail")
+    local head = tonumber(data[1]) or 0
+    local tail = tonumber(data[2]) or 0
+
+    local reqs = tail - head
+    if reqs + cost <= capacity then
+        tail = tail + cost
+        redis.call("HSET", key, "head", head, "tail", tail)
+        redis.call("PEXPIRE", key, ttl)
+        return {1, capacity - reqs - cost, tail}
     else
-        return 0
+        return {0, math.max(0, capacity - reqs), 0}
     end
 end
 
 local command = ARGV[1]
 local key = KEYS[1]
 if command == "take" then
-    local id = tostring(ARGV[2])
-    local capacity = tonumber(ARGV[3])
-    return take(key, id, capacity)
+    local capacity = tonumber(ARGV[2])
+    local cost = tonumber(ARGV[3] or 1)
+    local ttl = tonumber(ARGV[4])
+    return take(key, capacity, cost, ttl)
 elseif command == "core" then
     local no_of_reqs = tonumber(ARGV[2])
-    return drip_reqs(key, no_of_reqs)
+    local interval = tonumber(ARGV[3])
+    local ttl = tonumber(ARGV[4])
+    return drip_reqs(key, no_of_reqs, interval, ttl)
 else
     return redis.error_reply("Invalid command")
 end
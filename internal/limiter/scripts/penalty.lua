@@ -0,0 +1,49 @@
+-- penalty.lua
+
+-- function to get remaining ban of a client in milliseconds, 0 if not banned
+local function check(key)
+    local ttl = redis.call("PTTL", key .. ":ban")
+    if ttl > 0 then
+        return ttl
+    end
+    return 0
+end
+
+-- function to record a throttle and ban the client once threshold is reached
+-- ban doubles on every repeated offense till max ban
+local function strike(key, threshold, window, ban, max_ban, memory)
+    local strikes_key = key .. ":strikes"
+    local offenses_key = key .. ":offenses"
+
+    local strikes = redis.call("INCR", strikes_key)
+    if strikes == 1 then
+        redis.call("PEXPIRE", strikes_key, window)
+    end
+    if strikes < threshold then
+        return 0
+    end
+
+    -- escalating as per offenses still remembered
+    redis.call("DEL", strikes_key)
+    local offenses = redis.call("INCR", offenses_key)
+    redis.call("PEXPIRE", offenses_key, memory)
+
+    local duration = math.min(ban * math.pow(2, offenses - 1), max_ban)
+    redis.call("SET", key .. ":ban", 1, "PX", duration)
+    return duration
+end
+
+local command = ARGV[1]
+local key = KEYS[1]
+if command == "check" then
+    return check(key)
+elseif command == "strike" then
+    local threshold = tonumber(ARGV[2])
+    local window = tonumber(ARGV[3])
+    local ban = tonumber(ARGV[4])
+    local max_ban = tonumber(ARGV[5])
+    local memory = tonumber(ARGV[6])
+    return strike(key, threshold, window, ban, max_ban, memory)
+else
+    return redis.error_reply("Invalid command")
+end
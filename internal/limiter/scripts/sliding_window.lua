@@ -1,7 +1,29 @@
 -- sliding_window.lua
 
+-- function to get current time in milliseconds
+local function now_ms()
+    local time_data = redis.call("TIME")
+    return time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+end
+
+-- function to get no of intervals since the core last ran on any replica, 0 if it already ran in this one
+-- every replica runs its own timer on the shared window, so only the first one per interval does the work
+local function passed_intervals(key, interval, ttl)
+    local now = now_ms()
+    local slot = math.floor(now / interval)
+    local last = tonumber(redis.call("GET", key .. ":slot") or slot - 1)
+    redis.call("SET", key .. ":slot", slot, "PX", ttl)
+    return slot - last
+end
+
 -- function to reset requests in preious and current window
-local function reset_reqs(key)
+-- previous window is empty if more than one interval passed
+local function reset_reqs(key, interval)
+    local ttl = 2 * interval
+    local passed = passed_intervals(key, interval, ttl)
+    if passed <= 0 then
+        return 1
+    end
 
     -- intitializing all keys
     local curr_key = key .. ":curr"
@@ -9,18 +31,21 @@ local function reset_reqs(key)
     local time_key = key .. ":timeStamp"
 
     -- modifying all keys as per sliding window rules
-    local reqs = tonumber(redis.call("GET", curr_key) or 0)
-    redis.call("SET", curr_key, 0)
-    redis.call("SET", prev_key, reqs)
+    local reqs = 0
+    if passed == 1 then
+        reqs = tonumber(redis.call("GET", curr_key) or 0)
+    end
+    redis.call("SET", curr_key, 0, "PX", ttl)
+    redis.call("SET", prev_key, reqs, "PX", ttl)
 
-    local time = redis.call("TIME")
-    redis.call("SET", time_key, time[1])
+    -- current window starts at the boundary of its interval, in milliseconds like the interval
+    redis.call("SET", time_key, math.floor(now_ms() / interval) * interval, "PX", ttl)
 
     return 1
 end
 
--- function to permit requests
-local function take(key, no_of_reqs, interval)
+-- function to permit requests, returns whether permitted and space left
+local function take(key, no_of_reqs, interval, cost, ttl)
 
     -- intitializing all keys
     local curr_key = key .. ":curr"
@@ -32,10 +57,7 @@ local function take(key, no_of_reqs, interval)
     local prev = tonumber(redis.call("GET", prev_key) or 0)
     local timeStamp = tonumber(redis.call("GET", time_key) or 0)
 
-    local time = redis.call("TIME")
-    local now = tonumber(time[1]) -- Current timestamp from Redis
-
-    local elapsed = now - timeStamp
+    local elapsed = now_ms() - timeStamp
     local weight = (interval - elapsed) / interval
 
     if weight < 0 then
@@ -44,11 +66,12 @@ local function take(key, no_of_reqs, interval)
 
     local reqsInCurrSlidingWindow = prev * weight + curr
 
-    if reqsInCurrSlidingWindow < no_of_reqs then
-        redis.call("INCR", curr_key)
-        return 1
+    if reqsInCurrSlidingWindow + cost - 1 < no_of_reqs then
+        redis.call("INCRBY", curr_key, cost)
+        redis.call("PEXPIRE", curr_key, ttl)
+        return {1, math.max(0, math.floor(no_of_reqs - reqsInCurrSlidingWindow - cost))}
     else
-        return 0
+        return {0, math.max(0, math.floor(no_of_reqs - reqsInCurrSlidingWindow))}
     end
 end
 
@@ -57,9 +80,12 @@ local key = KEYS[1]
 if command == "take" then
     local no_of_reqs = tonumber(ARGV[2])
     local interval = tonumber(ARGV[3])
-    return take(key, no_of_reqs, interval)
+    local cost = tonumber(ARGV[4] or 1)
+    local ttl = tonumber(ARGV[5])
+    return take(key, no_of_reqs, interval, cost, ttl)
 elseif command == "core" then
-    return reset_reqs(key)
+    local interval = tonumber(ARGV[2])
+    return reset_reqs(key, interval)
 else
     return redis.error_reply("Invalid command")
 end
@@ -1,22 +1,43 @@
 -- sliding_window_log.lua
 
--- function to get oldest entry
-local function remove_logs(key)
-    local res = redis.call("LPOP", key)
-    return tonumber(res) or 0
+-- function to get current time in milliseconds
+local function now_ms()
+    local time_data = redis.call("TIME")
+    return time_data[1] * 1000 + math.floor(time_data[2] / 1000)
 end
 
--- function to log the returnsuest if queue has space
-local function take(key, no_of_reqs)
+-- function to remove logs older than the window, returns oldest log left or 0 if none
+-- newest logs are pushed to the front so the oldest ones are at the back
+-- removing by age gives the same result however many replicas run it
+local function remove_logs(key, interval)
+    local cutoff = now_ms() - interval
+    while true do
+        local res = tonumber(redis.call("LINDEX", key, -1))
+        if res == nil then
+            return 0
+        end
+        if res > cutoff then
+            return res
+        end
+        redis.call("RPOP", key)
+    end
+end
+
+-- function to log the returnsuest if queue has space, returns whether logged and space left
+local function take(key, no_of_reqs, cost, interval)
+    remove_logs(key, interval)
     local reqs = redis.call("LLEN", key)
-    if reqs < no_of_reqs then
-        local time_data = redis.call("TIME")
-        -- converting to milliseconds
-        local curr_time = time_data[1] * 1000 + math.floor(time_data[2] / 1000)
-        redis.call("LPUSH", key, tonumber(curr_time))
-        return 1
+    if reqs + cost <= no_of_reqs then
+        local curr_time = now_ms()
+        -- one log per unit consumed
+        for i = 1, cost do
+            redis.call("LPUSH", key, tonumber(curr_time))
+        end
+        -- window is empty once the newest log is this old
+        redis.call("PEXPIRE", key, interval)
+        return {1, no_of_reqs - reqs - cost}
     else
-        return 0
+        return {0, math.max(0, no_of_reqs - reqs)}
     end
 end
 
@@ -24,9 +45,12 @@ local command = ARGV[1]
 local key = KEYS[1]
 if command == "take" then
     local no_of_reqs = tonumber(ARGV[2])
-    return take(key, no_of_reqs)
+    local cost = tonumber(ARGV[3] or 1)
+    local interval = tonumber(ARGV[4])
+    return take(key, no_of_reqs, cost, interval)
 elseif command == "core" then
-    return remove_logs(key)
+    local interval = tonumber(ARGV[2])
+    return remove_logs(key, interval)
 else
     return redis.error_reply("Invalid command")
 end
@@ -1,39 +1,62 @@
 -- token_bucket.lua
 
--- function to refill tokens in the bucket
-local function refill_tokens(key, capacity, refill)
+-- function to get no of intervals since the core last ran on any replica, 0 if it already ran in this one
+-- every replica runs its own timer on the shared bucket, so only the first one per interval does the work
+local function passed_intervals(key, interval, ttl)
+    local time_data = redis.call("TIME")
+    -- converting to milliseconds
+    local now = time_data[1] * 1000 + math.floor(time_data[2] / 1000)
+    local slot = math.floor(now / interval)
+    local last = tonumber(redis.call("GET", key .. ":slot") or slot - 1)
+    redis.call("SET", key .. ":slot", slot, "PX", ttl)
+    return slot - last
+end
+
+-- function to refill tokens in the bucket for every interval passed
+-- missing bucket is full, so buckets dropped once idle or reset do not throttle
+local function refill_tokens(key, capacity, refill, interval, ttl)
     -- getting current tokens in bucket
-    local tokens = tonumber(redis.call("GET", key) or 0)
+    local tokens = tonumber(redis.call("GET", key) or capacity)
+
+    local passed = passed_intervals(key, interval, ttl)
+    if passed <= 0 then
+        return tokens
+    end
 
     -- refilling with new tokens
-    local newTokens = math.min(capacity, tokens + refill)
-    redis.call("SET", key, newTokens)
+    local newTokens = math.min(capacity, tokens + refill * passed)
+    redis.call("SET", key, newTokens, "PX", ttl)
 
     return newTokens
 end
 
--- function to permit request
-local function take(key)
+-- function to permit request, returns whether permitted and tokens left
+local function take(key, cost, capacity, ttl)
     -- getting current tokens in bucket
-    local tokens = tonumber(redis.call("GET", key) or 0)
+    local tokens = tonumber(redis.call("GET", key) or capacity)
 
-    -- take the token if bucket is not empty
-    if tokens > 0 then
-        redis.call("DECR", key)
-        return 1
+    -- take the tokens if bucket has enough
+    if tokens >= cost then
+        redis.call("SET", key, tokens - cost, "PX", ttl)
+        return {1, tokens - cost}
     else
-        return 0
+        return {0, tokens}
     end
 end
 
 local command = ARGV[1]
 local key = KEYS[1]
 if command == "take" then
-    return take(key)
+    local cost = tonumber(ARGV[2] or 1)
+    local capacity = tonumber(ARGV[3] or 0)
+    local ttl = tonumber(ARGV[4])
+    return take(key, cost, capacity, ttl)
 elseif command == "core" then
     local capacity = tonumber(ARGV[2])
     local refill = tonumber(ARGV[3])
-    return refill_tokens(key, capacity, refill)
+    local interval = tonumber(ARGV[4])
+    local ttl = tonumber(ARGV[5])
+    return refill_tokens(key, capacity, refill, interval, ttl)
 else
     return redis.error_reply("Invalid command")
 end
@@ -0,0 +1,466 @@
+// scripts_test.go
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// in-memory redis whose clock only moves when told to
+type testRedis struct {
+	mr  *miniredis.Miniredis
+	rdb *redis.Client
+	now time.Time
+}
+
+// function to start an in-memory redis closed at end of the test
+func newTestRedis(t *testing.T) *testRedis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// starting at a slot boundary of every interval used by the tests
+	tr := &testRedis{mr: mr, rdb: rdb, now: time.Unix(1700000000, 0)}
+	mr.SetTime(tr.now)
+	return tr
+}
+
+// function to move the clock of redis and expire keys as per the time passed
+func (tr *testRedis) advance(d time.Duration) {
+	tr.now = tr.now.Add(d)
+	tr.mr.SetTime(tr.now)
+	tr.mr.FastForward(d)
+}
+
+// function to run a script returning a list of integers
+func (tr *testRedis) slice(t *testing.T, name string, key string, args ...any) []int64 {
+	t.Helper()
+	res, err := Scripts[name].Run(context.Background(), tr.rdb, []string{key}, args...).Int64Slice()
+	if err != nil {
+		t.Fatalf("%s %v: %v", name, args, err)
+	}
+	return res
+}
+
+// function to run a script returning an integer
+func (tr *testRedis) int(t *testing.T, name string, key string, args ...any) int64 {
+	t.Helper()
+	res, err := Scripts[name].Run(context.Background(), tr.rdb, []string{key}, args...).Int64()
+	if err != nil {
+		t.Fatalf("%s %v: %v", name, args, err)
+	}
+	return res
+}
+
+// step of a script test, either a take checked against its outcome or time passing with a core run
+type step struct {
+	cost      int
+	allowed   bool
+	remaining int64
+
+	// time to pass before the step, core is run after it if set
+	wait time.Duration
+	core bool
+}
+
+func TestTokenBucketScript(t *testing.T) {
+	const capacity, refill = 5, 2
+	interval, ttl := time.Second, 4*time.Second
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "missing bucket is full", steps: []step{
+			{cost: 1, allowed: true, remaining: 4},
+		}},
+		{name: "drains and refuses", steps: []step{
+			{cost: 5, allowed: true, remaining: 0},
+			{cost: 1, allowed: false, remaining: 0},
+		}},
+		{name: "cost above tokens left", steps: []step{
+			{cost: 3, allowed: true, remaining: 2},
+			{cost: 3, allowed: false, remaining: 2},
+			{cost: 2, allowed: true, remaining: 0},
+		}},
+		{name: "refill per interval", steps: []step{
+			{cost: 5, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{cost: 2, allowed: true, remaining: 0},
+		}},
+		{name: "core once per interval", steps: []step{
+			{cost: 5, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{core: true},
+			{core: true},
+			{cost: 3, allowed: false, remaining: 2},
+		}},
+		{name: "catch up missed intervals", steps: []step{
+			{core: true},
+			{cost: 5, allowed: true, remaining: 0},
+			{wait: 2 * interval, core: true},
+			{cost: 4, allowed: true, remaining: 0},
+		}},
+		{name: "refill capped at capacity", steps: []step{
+			{cost: 1, allowed: true, remaining: 4},
+			{wait: 3 * interval, core: true},
+			{cost: 1, allowed: true, remaining: 4},
+		}},
+		{name: "idle bucket expires full", steps: []step{
+			{cost: 5, allowed: true, remaining: 0},
+			{wait: ttl + interval},
+			{cost: 5, allowed: true, remaining: 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRedis(t)
+			for i, s := range tt.steps {
+				tr.advance(s.wait)
+				if s.core {
+					tr.int(t, "TOKEN-BUCKET", "tb", "core", capacity, refill, millis(interval), millis(ttl))
+					continue
+				}
+				if s.wait > 0 {
+					continue
+				}
+				res := tr.slice(t, "TOKEN-BUCKET", "tb", "take", s.cost, capacity, millis(ttl))
+				if (res[0] == 1) != s.allowed || res[1] != s.remaining {
+					t.Fatalf("step %d: got %v, want allowed %v remaining %d", i, res, s.allowed, s.remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestFixedWindowScript(t *testing.T) {
+	const limit = 3
+	interval := time.Second
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "counts cost", steps: []step{
+			{cost: 2, allowed: true, remaining: 1},
+			{cost: 2, allowed: false, remaining: 1},
+			{cost: 1, allowed: true, remaining: 0},
+			{cost: 1, allowed: false, remaining: 0},
+		}},
+		{name: "new window resets", steps: []step{
+			{cost: 3, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{cost: 3, allowed: true, remaining: 0},
+		}},
+		{name: "reset once per window", steps: []step{
+			{wait: interval, core: true},
+			{cost: 3, allowed: true, remaining: 0},
+			{core: true},
+			{cost: 1, allowed: false, remaining: 0},
+		}},
+		{name: "window expires without core", steps: []step{
+			{cost: 3, allowed: true, remaining: 0},
+			{wait: 2 * interval},
+			{cost: 1, allowed: true, remaining: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRedis(t)
+			for i, s := range tt.steps {
+				tr.advance(s.wait)
+				if s.core {
+					tr.int(t, "FIXED-WINDOW", "fw", "core", millis(interval))
+					continue
+				}
+				if s.wait > 0 {
+					continue
+				}
+				res := tr.slice(t, "FIXED-WINDOW", "fw", "take", limit, s.cost, millis(interval))
+				if (res[0] == 1) != s.allowed || res[1] != s.remaining {
+					t.Fatalf("step %d: got %v, want allowed %v remaining %d", i, res, s.allowed, s.remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestSlidingWindowScript(t *testing.T) {
+	const limit = 4
+	interval := time.Second
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{name: "counts cost", steps: []step{
+			{cost: 3, allowed: true, remaining: 1},
+			{cost: 2, allowed: false, remaining: 1},
+			{cost: 1, allowed: true, remaining: 0},
+		}},
+		{name: "previous window still counts", steps: []step{
+			{cost: 4, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{cost: 1, allowed: false, remaining: 0},
+		}},
+		{name: "empty after two windows", steps: []step{
+			{cost: 4, allowed: true, remaining: 0},
+			{wait: 2 * interval, core: true},
+			{cost: 4, allowed: true, remaining: 0},
+		}},
+		{name: "previous window fades", steps: []step{
+			{core: true},
+			{cost: 4, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{wait: interval / 2},
+			{cost: 2, allowed: true, remaining: 0},
+			{cost: 1, allowed: false, remaining: 0},
+		}},
+		{name: "previous window gone by its end", steps: []step{
+			{core: true},
+			{cost: 4, allowed: true, remaining: 0},
+			{wait: interval, core: true},
+			{wait: interval - interval/4},
+			{cost: 3, allowed: true, remaining: 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRedis(t)
+			for i, s := range tt.steps {
+				tr.advance(s.wait)
+				if s.core {
+					tr.int(t, "SLIDING-WINDOW", "sw", "core", millis(interval))
+					continue
+				}
+				if s.wait > 0 {
+					continue
+				}
+				res := tr.slice(t, "SLIDING-WINDOW", "sw", "take", limit, millis(interval), s.cost, millis(2*interval))
+				if (res[0] == 1) != s.allowed || res[1] != s.remaining {
+					t.Fatalf("step %d: got %v, want allowed %v remaining %d", i, res, s.allowed, s.remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestSlidingWindowLogScript(t *testing.T) {
+	const limit = 3
+	interval := time.Second
+	tr := newTestRedis(t)
+
+	take := func(cost int) []int64 {
+		return tr.slice(t, "SLIDING-WINDOW-LOG", "swl", "take", limit, cost, millis(interval))
+	}
+
+	if res := take(2); res[0] != 1 || res[1] != 1 {
+		t.Fatalf("got %v, want allowed with 1 left", res)
+	}
+	tr.advance(400 * time.Millisecond)
+	if res := take(1); res[0] != 1 || res[1] != 0 {
+		t.Fatalf("got %v, want allowed with 0 left", res)
+	}
+	if res := take(1); res[0] != 0 {
+		t.Fatalf("got %v, want refused", res)
+	}
+
+	// oldest logs leave the window first
+	oldest := tr.now.Add(-400 * time.Millisecond).UnixMilli()
+	if got := tr.int(t, "SLIDING-WINDOW-LOG", "swl", "core", millis(interval)); got != oldest {
+		t.Fatalf("got oldest %d, want %d", got, oldest)
+	}
+	tr.advance(600 * time.Millisecond)
+	if got := tr.int(t, "SLIDING-WINDOW-LOG", "swl", "core", millis(interval)); got != tr.now.Add(-600*time.Millisecond).UnixMilli() {
+		t.Fatalf("got oldest %d after window passed", got)
+	}
+	if res := take(2); res[0] != 1 || res[1] != 0 {
+		t.Fatalf("got %v, want allowed with 0 left", res)
+	}
+
+	// empty window once the newest log is older than it
+	tr.advance(interval)
+	if got := tr.int(t, "SLIDING-WINDOW-LOG", "swl", "core", millis(interval)); got != 0 {
+		t.Fatalf("got oldest %d, want empty", got)
+	}
+}
+
+func TestLeakyBucketScript(t *testing.T) {
+	const capacity, drip = 4, 2
+	interval, ttl := time.Second, 4*time.Second
+	tr := newTestRedis(t)
+
+	take := func(cost int) []int64 {
+		return tr.slice(t, "LEAKY-BUCKET", "lb", "take", capacity, cost, millis(ttl))
+	}
+	core := func() int64 {
+		return tr.int(t, "LEAKY-BUCKET", "lb", "core", drip, millis(interval), millis(ttl))
+	}
+
+	// positions are the last slot taken by each request
+	tests := []struct {
+		cost     int
+		allowed  bool
+		position int64
+	}{
+		{cost: 1, allowed: true, position: 1},
+		{cost: 2, allowed: true, position: 3},
+		{cost: 2, allowed: false},
+		{cost: 1, allowed: true, position: 4},
+	}
+	for i, tt := range tests {
+		res := take(tt.cost)
+		if (res[0] == 1) != tt.allowed || res[2] != tt.position {
+			t.Fatalf("take %d: got %v, want allowed %v at %d", i, res, tt.allowed, tt.position)
+		}
+	}
+
+	// head moves by the drip rate once per interval, whatever the no of replicas running core
+	tr.advance(interval)
+	if head := core(); head != 2 {
+		t.Fatalf("got head %d, want 2", head)
+	}
+	if head := core(); head != 2 {
+		t.Fatalf("got head %d after second core, want 2", head)
+	}
+	if res := take(2); res[0] != 1 || res[2] != 6 {
+		t.Fatalf("got %v, want allowed at 6", res)
+	}
+
+	// catching up missed intervals, never past the tail
+	tr.advance(3 * interval)
+	if head := core(); head != 6 {
+		t.Fatalf("got head %d, want 6", head)
+	}
+
+	// idle queue is dropped, positions start over
+	tr.advance(ttl + interval)
+	if res := take(1); res[0] != 1 || res[2] != 1 {
+		t.Fatalf("got %v, want allowed at 1", res)
+	}
+}
+
+func TestConcurrencyScript(t *testing.T) {
+	tr := newTestRedis(t)
+	ttl := time.Minute
+	run := func(args ...any) int64 {
+		return tr.int(t, "CONCURRENCY", "streams", args...)
+	}
+
+	if run("take", "a", 2, millis(ttl)) != 1 || run("take", "b", 2, millis(ttl)) != 1 {
+		t.Fatal("streams below limit refused")
+	}
+	if run("take", "c", 2, millis(ttl)) != 0 {
+		t.Fatal("stream above limit taken")
+	}
+	if run("release", "a") != 1 || run("take", "c", 2, millis(ttl)) != 1 {
+		t.Fatal("released slot not reused")
+	}
+
+	// streams not refreshed expire so crashed replicas do not leak slots
+	tr.advance(ttl / 2)
+	run("refresh", "b", millis(ttl))
+	tr.advance(ttl/2 + time.Second)
+	if run("take", "d", 2, millis(ttl)) != 1 {
+		t.Fatal("expired stream still counted")
+	}
+	if run("take", "e", 2, millis(ttl)) != 0 {
+		t.Fatal("refreshed stream not counted")
+	}
+}
+
+func TestBandwidthScript(t *testing.T) {
+	tr := newTestRedis(t)
+	const rate, burst = 1000, 1000
+	take := func(bytes int) int64 {
+		return tr.int(t, "BANDWIDTH", "bw", "take", rate, burst, bytes)
+	}
+
+	if wait := take(800); wait != 0 {
+		t.Fatalf("got wait %d, want 0", wait)
+	}
+	if wait := take(500); wait != 300 {
+		t.Fatalf("got wait %d, want 300", wait)
+	}
+	tr.advance(300 * time.Millisecond)
+	if wait := take(500); wait != 0 {
+		t.Fatalf("got wait %d after refill, want 0", wait)
+	}
+}
+
+func TestPenaltyScript(t *testing.T) {
+	tr := newTestRedis(t)
+	window, ban, maxBan, memory := time.Minute, 10*time.Second, 25*time.Second, time.Hour
+	strike := func() int64 {
+		return tr.int(t, "PENALTY", "client", "strike", 2, millis(window), millis(ban), millis(maxBan), millis(memory))
+	}
+	check := func() int64 {
+		return tr.int(t, "PENALTY", "client", "check")
+	}
+
+	// ban doubles on every offense till the max ban
+	for i, want := range []time.Duration{ban, 2 * ban, maxBan} {
+		if got := strike(); got != 0 {
+			t.Fatalf("offense %d: got ban %d on first strike", i, got)
+		}
+		if got := strike(); got != millis(want) {
+			t.Fatalf("offense %d: got ban %d, want %d", i, got, millis(want))
+		}
+		if got := check(); got <= 0 || got > millis(want) {
+			t.Fatalf("offense %d: got remaining ban %d", i, got)
+		}
+		tr.advance(want)
+		if got := check(); got != 0 {
+			t.Fatalf("offense %d: got remaining ban %d after it passed", i, got)
+		}
+	}
+
+	// strikes older than the window are forgotten
+	strike()
+	tr.advance(window)
+	if got := strike(); got != 0 {
+		t.Fatalf("got ban %d from strikes out of window", got)
+	}
+}
+
+func TestLimitersShareKeys(t *testing.T) {
+	// limiters wait on the local clock, so redis keeps the same one
+	tr := newTestRedis(t)
+	tr.mr.SetTime(time.Now())
+
+	for _, strategy := range []string{"TOKEN-BUCKET", "FIXED-WINDOW", "SLIDING-WINDOW", "SLIDING-WINDOW-LOG"} {
+		t.Run(strategy, func(t *testing.T) {
+			// two replicas of the same limit, timers never fire during the test
+			rateLimit := &utils.RateLimit{
+				ID:           utils.LimitID("shared", "-", strategy),
+				Strategy:     strategy,
+				Capacity:     2,
+				NoOfRequests: 2,
+				TimeDuration: time.Hour,
+			}
+			a := Limiters[strategy](tr.rdb, rateLimit)
+			b := Limiters[strategy](tr.rdb, rateLimit)
+			defer a.Stop()
+			defer b.Stop()
+
+			ctx := context.Background()
+			allowed := 0
+			for i := 0; i < 3; i++ {
+				for _, l := range []Limiter{a, b} {
+					if l.Admit(ctx, NewRequest("r")).Allowed {
+						allowed++
+					}
+				}
+			}
+			if allowed != 2 {
+				t.Errorf("got %d allowed across replicas, want 2", allowed)
+			}
+		})
+	}
+}
@@ -3,15 +3,17 @@ package limiter
 
 import (
 	"context"
-	"log"
-	"net/http/httputil"
+	"log/slog"
 	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type SlidingWindow struct {
+	// redis client holding state of the limiter
+	rdb *redis.Client
+
 	// key to track requests
 	key string
 
@@ -24,27 +26,29 @@ type SlidingWindow struct {
 	// context for closure
 	ctx    context.Context
 	cancel context.CancelFunc
-
-	// corresponding proxy
-	proxy *httputil.ReverseProxy
 }
 
 // constructor to initialize window
-func NewSlidingWindow(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter {
+func NewSlidingWindow(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter {
+	sw := newSlidingWindow(rdb, rateLimit)
+
+	// starting the resetting of window as a go routine once it is initalized
+	go sw.reset()
+
+	return sw
+}
+
+// function to initialize window without resetting it
+func newSlidingWindow(rdb *redis.Client, rateLimit *utils.RateLimit) *SlidingWindow {
 	ctx, cancel := context.WithCancel(context.Background())
-	sw := &SlidingWindow{
-		key:          uuid.NewString(),
+	return &SlidingWindow{
+		rdb:          rdb,
+		key:          limiterKey(rateLimit),
 		ctx:          ctx,
 		cancel:       cancel,
-		proxy:        proxy,
 		noOfRequests: rateLimit.NoOfRequests,
 		interval:     rateLimit.TimeDuration,
 	}
-
-	// starting the resetting of window as a go routine once it is initalized
-	go sw.reset()
-
-	return sw
 }
 
 // core functionality of the algorithm the resetting of window
@@ -59,7 +63,7 @@ func (sw *SlidingWindow) reset() {
 
 		// refill as per rate
 		case <-ticker.C:
-			Scripts["SLIDING-WINDOW"].Run(sw.ctx, Rdb, []string{sw.key}, "core")
+			sw.Step()
 
 		// returning from function if context is cancelled
 		case <-sw.ctx.Done():
@@ -69,26 +73,24 @@ func (sw *SlidingWindow) reset() {
 
 }
 
-// core functionality 2 of the algorithm calculation of dynamic window size
-// function to increment requests in window and process the request
-func (sw *SlidingWindow) AddRequest(req *Request) bool {
-	// check if request can be permitted
-	res, err := Scripts["SLIDING-WINDOW"].Run(sw.ctx, Rdb, []string{sw.key}, "take", sw.noOfRequests, sw.interval).Int()
+// function to move the window once, as done every interval
+func (sw *SlidingWindow) Step() {
+	Scripts["SLIDING-WINDOW"].Run(sw.ctx, sw.rdb, []string{sw.key}, "core", millis(sw.interval))
+}
 
+// core functionality 2 of the algorithm calculation of dynamic window size
+// function to increment requests in window if request is permitted
+func (sw *SlidingWindow) Admit(ctx context.Context, req *Request) Decision {
+	// check if request is permitted
+	res, err := Scripts["SLIDING-WINDOW"].Run(traced(sw.ctx, ctx), sw.rdb, []string{sw.key}, "take", sw.noOfRequests, millis(sw.interval), req.Cost, millis(2*sw.interval)).Int64Slice()
 	if err != nil {
-		log.Println("Error:", err)
-		return false
+		slog.ErrorContext(ctx, "Error running script", "error", err)
+		return deny(-1)
 	}
-	if res == 1 {
-
-		// serve request
-		go ServeReq(sw.proxy, req, nil)
-
-		return true
-	} else {
-		return false
+	if res[0] == 1 {
+		return Decision{Allowed: true, Remaining: int(res[1])}
 	}
-
+	return deny(int(res[1]))
 }
 
 // function to stop the algorithm
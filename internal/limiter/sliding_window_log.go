@@ -4,15 +4,18 @@ package limiter
 import (
 	"context"
 	"log"
-	"net/http/httputil"
+	"log/slog"
 	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type SlidingWindowLog struct {
 
+	// redis client holding state of the limiter
+	rdb *redis.Client
+
 	// key to track current sliding window
 	key string
 
@@ -25,27 +28,29 @@ type SlidingWindowLog struct {
 	// context for closure
 	ctx    context.Context
 	cancel context.CancelFunc
-
-	// corresponding proxy
-	proxy *httputil.ReverseProxy
 }
 
 // constructor to initialize window
-func NewSlidingWindowLog(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter {
+func NewSlidingWindowLog(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter {
+	swl := newSlidingWindowLog(rdb, rateLimit)
+
+	// starting the removal of logs from window as a go routine once it is initalized
+	go swl.removeLogs()
+
+	return swl
+}
+
+// function to initialize window without removing its logs
+func newSlidingWindowLog(rdb *redis.Client, rateLimit *utils.RateLimit) *SlidingWindowLog {
 	ctx, cancel := context.WithCancel(context.Background())
-	swl := &SlidingWindowLog{
-		key:          uuid.NewString(),
+	return &SlidingWindowLog{
+		rdb:          rdb,
+		key:          limiterKey(rateLimit),
 		ctx:          ctx,
 		cancel:       cancel,
-		proxy:        proxy,
 		noOfRequests: rateLimit.NoOfRequests,
 		interval:     rateLimit.TimeDuration,
 	}
-
-	// starting the removal of logs from window as a go routine once it is initalized
-	go swl.removeLogs()
-
-	return swl
 }
 
 // core functionality of the algorithm the removal of logs from window
@@ -58,42 +63,53 @@ func (swl *SlidingWindowLog) removeLogs() {
 			return
 		// removing the expired log
 		default:
-			// get front of the queue
-			front, err := Scripts["SLIDING-WINDOW-LOG"].Run(swl.ctx, Rdb, []string{swl.key}, "core").Int()
-			if err != nil {
-				log.Printf("Error :%v", err)
+			front := swl.prune()
+
+			// check the target time, a whole window if nothing is logged
+			targetTime := time.Now().Add(swl.interval)
+			if front > 0 {
+				targetTime = time.UnixMilli(front).Add(swl.interval)
+			}
+
+			// sleep untill target time or closure
+			timer := time.NewTimer(time.Until(targetTime))
+			select {
+			case <-timer.C:
+			case <-swl.ctx.Done():
+				timer.Stop()
+				return
 			}
-			
-			// convert to unix milli
-			t := time.UnixMilli(int64(front))
-			
-			// check the target time
-			targetTime := t.Add(swl.interval)
-
-			// sleep untill target time
-			time.Sleep(time.Until(targetTime))
 		}
 	}
 
 }
 
-// function to increment requests in window and process the request
-func (swl *SlidingWindowLog) AddRequest(req *Request) bool {
-	// chek if request is permitted
-	res, err := Scripts["SLIDING-WINDOW-LOG"].Run(swl.ctx, Rdb, []string{swl.key}, "take", swl.noOfRequests).Int()
+// function to remove expired logs and get the oldest one left, 0 if none
+func (swl *SlidingWindowLog) prune() int64 {
+	front, err := Scripts["SLIDING-WINDOW-LOG"].Run(swl.ctx, swl.rdb, []string{swl.key}, "core", millis(swl.interval)).Int64()
 	if err != nil {
 		log.Printf("Error :%v", err)
 	}
+	return front
+}
 
-	if res == 1 {
-		// serve request
-		go ServeReq(swl.proxy, req, nil)
-		return true
-	} else {
-		return false
+// function to remove expired logs once, as done whenever the oldest one expires
+func (swl *SlidingWindowLog) Step() {
+	swl.prune()
+}
 
+// function to increment requests in window if request is permitted
+func (swl *SlidingWindowLog) Admit(ctx context.Context, req *Request) Decision {
+	// check if request is permitted
+	res, err := Scripts["SLIDING-WINDOW-LOG"].Run(traced(swl.ctx, ctx), swl.rdb, []string{swl.key}, "take", swl.noOfRequests, req.Cost, millis(swl.interval)).Int64Slice()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error running script", "error", err)
+		return deny(-1)
 	}
-
+	if res[0] == 1 {
+		return Decision{Allowed: true, Remaining: int(res[1])}
+	}
+	return deny(int(res[1]))
 }
 
 // function to stop the algorithm
@@ -3,16 +3,18 @@ package limiter
 
 import (
 	"context"
-	"log"
-	"net/http/httputil"
+	"log/slog"
 	"time"
 
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type TokenBucket struct {
 
+	// redis client holding state of the limiter
+	rdb *redis.Client
+
 	// key to track current tokens in bucket
 	key string
 
@@ -25,30 +27,38 @@ type TokenBucket struct {
 	// bucket refill duration
 	interval time.Duration
 
+	// time after which an untouched bucket is full again and dropped
+	ttl time.Duration
+
 	// context for closure
 	ctx    context.Context
 	cancel context.CancelFunc
-
-	// corresponding proxy
-	proxy *httputil.ReverseProxy
 }
 
 // constructor to initialize token bucket
-func NewTokenBucket(rateLimit *utils.RateLimit, proxy *httputil.ReverseProxy) Limiter {
+func NewTokenBucket(rdb *redis.Client, rateLimit *utils.RateLimit) Limiter {
+	tb := newTokenBucket(rdb, rateLimit)
+
+	// starting the refilling of bucket as a go routine once it is initalized
+	go tb.refill()
+
+	return tb
+}
+
+// function to initialize token bucket without refilling it
+func newTokenBucket(rdb *redis.Client, rateLimit *utils.RateLimit) *TokenBucket {
 	ctx, cancel := context.WithCancel(context.Background())
 	tb := &TokenBucket{
-		key:          uuid.NewString(),
+		rdb:          rdb,
+		key:          limiterKey(rateLimit),
 		capacity:     rateLimit.Capacity,
 		ctx:          ctx,
 		cancel:       cancel,
-		proxy:        proxy,
 		noOfRequests: rateLimit.NoOfRequests,
 		interval:     rateLimit.TimeDuration,
 	}
-
-	// starting the refilling of bucket as a go routine once it is initalized
-	go tb.refill()
-
+	refills := (tb.capacity + tb.noOfRequests - 1) / max(tb.noOfRequests, 1)
+	tb.ttl = time.Duration(refills+1) * tb.interval
 	return tb
 }
 
@@ -64,9 +74,7 @@ func (tb *TokenBucket) refill() {
 
 		// refill as per rate
 		case <-ticker.C:
-
-			// update to whatever is minimum
-			Scripts["TOKEN-BUCKET"].Run(tb.ctx, Rdb, []string{tb.key}, "core", tb.capacity, tb.noOfRequests).Int()
+			tb.Step()
 
 		// returning from function if context is cancelled
 		case <-tb.ctx.Done():
@@ -76,22 +84,24 @@ func (tb *TokenBucket) refill() {
 
 }
 
-// function to take token and process the request
-func (tb *TokenBucket) AddRequest(req *Request) bool {
+// function to refill the bucket once, as done every interval
+func (tb *TokenBucket) Step() {
+	// update to whatever is minimum
+	Scripts["TOKEN-BUCKET"].Run(tb.ctx, tb.rdb, []string{tb.key}, "core", tb.capacity, tb.noOfRequests, millis(tb.interval), millis(tb.ttl)).Int()
+}
 
-	// check if request can be served
-	res, err := Scripts["TOKEN-BUCKET"].Run(tb.ctx, Rdb, []string{tb.key}, "take").Int()
+// function to take token if request is permitted
+func (tb *TokenBucket) Admit(ctx context.Context, req *Request) Decision {
+	// check if request is permitted
+	res, err := Scripts["TOKEN-BUCKET"].Run(traced(tb.ctx, ctx), tb.rdb, []string{tb.key}, "take", req.Cost, tb.capacity, millis(tb.ttl)).Int64Slice()
 	if err != nil {
-		log.Println("Error:", err)
-		return false
+		slog.ErrorContext(ctx, "Error running script", "error", err)
+		return deny(-1)
 	}
-	// serve request if permitted
-	if res == 1 {
-		go ServeReq(tb.proxy, req, nil)
-		return true
-	} else {
-		return false
+	if res[0] == 1 {
+		return Decision{Allowed: true, Remaining: int(res[1])}
 	}
+	return deny(int(res[1]))
 }
 
 // function to stop the algorithm
@@ -0,0 +1,85 @@
+// tracing.go
+package limiter
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer of limiter spans
+var tracer = otel.Tracer("github.com/Sp92535/GoGate-RateLimiter/internal/limiter")
+
+// function to carry span of the caller into context of the limiter
+func traced(ctx context.Context, caller context.Context) context.Context {
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(caller))
+}
+
+// redis hook tracing lua script calls made on behalf of requests
+type scriptTracing struct {
+	// mapping of script hash -> strategy
+	names map[string]string
+}
+
+// constructor to initialize tracing of all scripts
+func newScriptTracing(scripts map[string]*redis.Script) *scriptTracing {
+	st := &scriptTracing{names: make(map[string]string)}
+	for name, script := range scripts {
+		st.names[script.Hash()] = name
+	}
+	return st
+}
+
+func (st *scriptTracing) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// function to wrap script calls in a span, background calls without a request are not traced
+func (st *scriptTracing) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		name := cmd.Name()
+		if (name != "evalsha" && name != "eval") || !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmd)
+		}
+
+		// args are command, script or hash, no of keys, keys then argv
+		script := "unknown"
+		args := cmd.Args()
+		if name == "evalsha" && len(args) > 1 {
+			if s, exists := st.names[args[1].(string)]; exists {
+				script = s
+			}
+		}
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", "redis"),
+			attribute.String("gogate.script", script),
+		}
+		if len(args) > 4 {
+			if command, ok := args[4].(string); ok {
+				attrs = append(attrs, attribute.String("gogate.script.command", command))
+			}
+		}
+
+		ctx, span := tracer.Start(ctx, "redis "+strings.ToLower(script), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		err := next(ctx, cmd)
+		// missing script is expected before the script is loaded
+		if err != nil && err != redis.Nil && !redis.HasErrorPrefix(err, "NOSCRIPT") {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
+
+func (st *scriptTracing) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+var _ redis.Hook = (*scriptTracing)(nil)
@@ -0,0 +1,51 @@
+// metrics.go
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prefix for all metrics
+const namespace = "gogate"
+
+// requests per resource, method and limiter decision
+var Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "requests_total",
+	Help:      "Requests by limiter decision: allowed, throttled or dry_run_throttled.",
+}, []string{"resource", "method", "decision"})
+
+// function to expose health and load of a destination
+func RegisterDestination(resource string, destination string, available func() bool, conns func() int64) {
+	labels := prometheus.Labels{"resource": resource, "destination": destination}
+
+	prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "destination_healthy",
+		Help:        "Whether the destination is receiving traffic (1) or not (0).",
+		ConstLabels: labels,
+	}, func() float64 {
+		if available() {
+			return 1
+		}
+		return 0
+	}))
+
+	prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "destination_active_connections",
+		Help:        "Number of requests currently being served by the destination.",
+		ConstLabels: labels,
+	}, func() float64 {
+		return float64(conns())
+	}))
+}
+
+// function to get handler serving all metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
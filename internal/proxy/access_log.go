@@ -0,0 +1,116 @@
+// access_log.go
+package proxy
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/auth"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// response writer recording status and size of the response
+type accessWriter struct {
+	http.ResponseWriter
+
+	// status code sent, 0 until headers are written
+	status int
+
+	// no of body bytes sent
+	bytes int64
+}
+
+// function to record status code
+func (aw *accessWriter) WriteHeader(status int) {
+	// informational responses are followed by the actual one
+	if aw.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		aw.status = status
+	}
+	aw.ResponseWriter.WriteHeader(status)
+}
+
+// function to record size of the response
+func (aw *accessWriter) Write(p []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	n, err := aw.ResponseWriter.Write(p)
+	aw.bytes += int64(n)
+	return n, err
+}
+
+// function to flush streaming responses
+func (aw *accessWriter) Flush() {
+	http.NewResponseController(aw.ResponseWriter).Flush()
+}
+
+// function to take over connection of upgraded requests
+func (aw *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusSwitchingProtocols
+	}
+	return http.NewResponseController(aw.ResponseWriter).Hijack()
+}
+
+// function to expose underlying writer to response controller
+func (aw *accessWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+// outcome of a request written to the access log
+type accessEntry struct {
+	id     string
+	start  time.Time
+	client string
+
+	// decision taken for the request, same as the metric label
+	decision string
+
+	// units left in the limit, -1 if unknown
+	remaining int
+
+	// request was only checked for a fronting proxy, not forwarded
+	checked bool
+
+	// writer recording the response
+	w *accessWriter
+}
+
+// function to write access log entry of a request
+func (h *Handler) logAccess(r *http.Request, entry *accessEntry) {
+	// throttled and rejected requests are warnings, failures of destinations are errors
+	level := slog.LevelInfo
+	switch {
+	case entry.w.status >= 500:
+		level = slog.LevelError
+	case entry.w.status >= 400:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("resource", h.name),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("client", entry.client),
+		slog.String("decision", entry.decision),
+		slog.Int("status", entry.w.status),
+		slog.Int64("bytes", entry.w.bytes),
+		slog.Duration("duration", time.Since(entry.start)),
+	}
+	if identity := auth.FromRequest(r); identity != nil && identity.Subject != "" {
+		attrs = append(attrs, slog.String("subject", identity.Subject))
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+	}
+	if entry.remaining >= 0 {
+		attrs = append(attrs, slog.Int("remaining", entry.remaining))
+	}
+	if entry.checked {
+		attrs = append(attrs, slog.Bool("forward_auth", true))
+	}
+	slog.LogAttrs(r.Context(), level, "access", attrs...)
+}
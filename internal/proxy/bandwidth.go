@@ -0,0 +1,134 @@
+// bandwidth.go
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// largest no of bytes transferred per redis call
+const maxChunk = 32 * 1024
+
+// bandwidth limits of request and response bodies
+type bandwidthLimits struct {
+	// redis client holding the buckets
+	rdb *redis.Client
+
+	// prefix of keys tracking the buckets
+	key string
+
+	cfg *utils.BandwidthLimit
+}
+
+// constructor to initialize bandwidth limits for a resource and method
+func newBandwidthLimits(name string, method string, cfg *utils.BandwidthLimit, rdb *redis.Client) *bandwidthLimits {
+	if cfg == nil {
+		return nil
+	}
+	return &bandwidthLimits{
+		rdb: rdb,
+		key: "gogate:bandwidth:" + name + ":" + method,
+		cfg: cfg,
+	}
+}
+
+// function to throttle request body and response writer
+func (bl *bandwidthLimits) wrap(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	key := bl.key
+	if bl.cfg.PerClient {
+		key += ":" + ClientKey(r)
+	}
+
+	if bl.cfg.UploadBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+		r.Body = &throttledReader{
+			ReadCloser: r.Body,
+			ctx:        r.Context(),
+			bandwidth:  limiter.NewBandwidth(bl.rdb, key+":upload", bl.cfg.UploadBytes, 0),
+		}
+	}
+	if bl.cfg.DownloadBytes > 0 {
+		w = newThrottledWriter(w, r.Context(), limiter.NewBandwidth(bl.rdb, key+":download", bl.cfg.DownloadBytes, 0))
+	}
+	return w
+}
+
+// request body not exceeding the bandwidth
+type throttledReader struct {
+	io.ReadCloser
+
+	// context of the request
+	ctx context.Context
+
+	bandwidth *limiter.Bandwidth
+}
+
+// function to read body and wait for the bytes read
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	p = p[:min(len(p), tr.bandwidth.Burst(), maxChunk)]
+	n, err := tr.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := tr.bandwidth.Wait(tr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// response writer not exceeding the bandwidth
+type throttledWriter struct {
+	http.ResponseWriter
+
+	// context of the request
+	ctx context.Context
+
+	bandwidth *limiter.Bandwidth
+}
+
+// constructor to initialize throttled writer
+func newThrottledWriter(w http.ResponseWriter, ctx context.Context, bandwidth *limiter.Bandwidth) *throttledWriter {
+	return &throttledWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		bandwidth:      bandwidth,
+	}
+}
+
+// function to write response in chunks not exceeding the bandwidth
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), tw.bandwidth.Burst(), maxChunk)]
+		if err := tw.bandwidth.Wait(tw.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := tw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// function to flush written data to client
+func (tw *throttledWriter) Flush() {
+	http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// function to take over the connection, upgraded connections are not throttled
+func (tw *throttledWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(tw.ResponseWriter).Hijack()
+}
+
+// function to get underlying response writer
+func (tw *throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,56 @@
+// cost.go
+package proxy
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// rules to derive no of units consumed by a request
+type costRules struct {
+	cfg *utils.Cost
+}
+
+// constructor to initialize cost rules, patterns are validated while loading the limit
+func newCostRules(cfg *utils.Cost) *costRules {
+	return &costRules{cfg: cfg}
+}
+
+// function to get cost of a request
+// first matching path pattern, else default, raised by header or else query parameter
+func (cr *costRules) of(r *http.Request) int {
+	cost := max(cr.lookup(r), cr.requested(r))
+	if cr.cfg.Max > 0 && cost > cr.cfg.Max {
+		cost = cr.cfg.Max
+	}
+	return max(cost, 1)
+}
+
+// function to find the configured cost applying to a request
+func (cr *costRules) lookup(r *http.Request) int {
+	for _, rule := range cr.cfg.Paths {
+		if ok, _ := path.Match(rule.Pattern, r.URL.Path); ok {
+			return rule.Cost
+		}
+	}
+	return cr.cfg.Default
+}
+
+// function to get cost named by the client, 0 if none
+// clients may only ask for more, so a cheap value can not undercut the configured cost
+func (cr *costRules) requested(r *http.Request) int {
+	if cr.cfg.Header != "" {
+		if cost, err := strconv.Atoi(r.Header.Get(cr.cfg.Header)); err == nil {
+			return cost
+		}
+	}
+	if cr.cfg.Query != "" {
+		if cost, err := strconv.Atoi(r.URL.Query().Get(cr.cfg.Query)); err == nil {
+			return cost
+		}
+	}
+	return 0
+}
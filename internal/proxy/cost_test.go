@@ -0,0 +1,66 @@
+// cost_test.go
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+func TestCostRules(t *testing.T) {
+	cfg := &utils.Cost{
+		Default: 2,
+		Header:  "X-Cost",
+		Query:   "cost",
+		Paths: []utils.PathCost{
+			{Pattern: "/export/*", Cost: 50},
+			{Pattern: "/export/*", Cost: 5},
+			{Pattern: "/free", Cost: 0},
+		},
+		Max: 100,
+	}
+	rules := newCostRules(cfg)
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{name: "default", target: "/x", want: 2},
+		{name: "header raises", target: "/x", header: "7", want: 7},
+		{name: "header before query", target: "/x?cost=9", header: "7", want: 7},
+		{name: "query raises", target: "/x?cost=9", want: 9},
+		{name: "bad header falls through", target: "/x?cost=9", header: "many", want: 9},
+		{name: "first path wins", target: "/export/csv", want: 50},
+		{name: "header can not lower path", target: "/export/csv", header: "1", want: 50},
+		{name: "query can not lower path", target: "/export/csv?cost=0", want: 50},
+		{name: "header can not lower default", target: "/x", header: "1", want: 2},
+		{name: "header raises path", target: "/export/csv", header: "80", want: 80},
+		{name: "pattern stays in segment", target: "/export/csv/all", want: 2},
+		{name: "capped at max", target: "/x", header: "1000", want: 100},
+		{name: "at least one", target: "/free", want: 1},
+		{name: "negative is ignored", target: "/x", header: "-5", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set("X-Cost", tt.header)
+			}
+			if got := rules.of(r); got != tt.want {
+				t.Errorf("got cost %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostRulesNoMax(t *testing.T) {
+	rules := newCostRules(&utils.Cost{Header: "X-Cost"})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Cost", "100000")
+	if got := rules.of(r); got != 100000 {
+		t.Errorf("got cost %d, want 100000", got)
+	}
+}
@@ -0,0 +1,185 @@
+// forward_auth.go
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/router"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// headers carrying method of the original request, as sent by traefik and caddy or set for nginx
+var originalMethodHeaders = []string{"X-Forwarded-Method", "X-Original-Method"}
+
+// headers carrying uri of the original request, as sent by traefik and caddy or set for nginx
+var originalURIHeaders = []string{"X-Forwarded-Uri", "X-Original-URI"}
+
+// handler answering auth subrequests of fronting proxies with decisions of the resources
+type ForwardAuth struct {
+	// router matching original requests to resources
+	router *router.Router
+
+	// proxies allowed to send subrequests, any if empty
+	proxies []netip.Prefix
+
+	// headers the client address is taken from, first one present wins, only honored from trusted proxies
+	clientIPHeaders []string
+
+	// header carrying host of the original request, host of the subrequest if empty
+	hostHeader string
+}
+
+// constructor to initialize forward auth over routes of all resources
+func NewForwardAuth(rtr *router.Router, cfg *utils.ForwardAuth) *ForwardAuth {
+	fa := &ForwardAuth{
+		router:          rtr,
+		clientIPHeaders: cfg.ClientIPHeaders,
+		hostHeader:      cfg.HostHeader,
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				log.Fatalf("invalid trusted proxy %s", proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		fa.proxies = append(fa.proxies, prefix.Masked())
+	}
+
+	// anyone could name the client, so the peer is taken instead
+	if len(fa.clientIPHeaders) > 0 && len(fa.proxies) == 0 {
+		log.Printf("forward auth ignores client ip headers %v without trusted proxies", fa.clientIPHeaders)
+	}
+	return fa
+}
+
+// function to decide on the original request, 200 lets it through
+func (fa *ForwardAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(fa.proxies) > 0 && !fa.trusted(remoteAddr(r.RemoteAddr)) {
+		http.Error(w, "403 Forbidden: untrusted proxy", http.StatusForbidden)
+		return
+	}
+
+	orig, err := fa.originalRequest(r)
+	if err != nil {
+		http.Error(w, "400 Bad Request: invalid original uri", http.StatusBadRequest)
+		return
+	}
+
+	// requests of no resource are not limited
+	route := fa.router.Resolve(orig)
+	if route == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	h, ok := route.Handler().(*Handler)
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	h.Check(w, orig)
+}
+
+// function to rebuild the request received by the fronting proxy from an auth subrequest
+// the subrequest itself is used where the proxy passes nothing, so the original uri may be the path
+func (fa *ForwardAuth) originalRequest(r *http.Request) (*http.Request, error) {
+	orig := r.Clone(r.Context())
+
+	if method := firstHeader(r, originalMethodHeaders); method != "" {
+		orig.Method = strings.ToUpper(method)
+	}
+
+	uri := firstHeader(r, originalURIHeaders)
+	if uri == "" {
+		uri = r.RequestURI
+	}
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	orig.URL = u
+	orig.RequestURI = uri
+
+	// clients may send any of these headers, so only the configured one is taken
+	if fa.hostHeader != "" {
+		if host := r.Header.Get(fa.hostHeader); host != "" {
+			orig.Host = host
+		}
+	}
+
+	// client as seen by the fronting proxy, the subrequest's peer if no header names it
+	// headers are taken only from trusted proxies, so no client can name another one
+	if fa.trusted(remoteAddr(r.RemoteAddr)) {
+		for _, header := range fa.clientIPHeaders {
+			if ip := fa.clientIP(r.Header.Values(header)); ip.IsValid() {
+				orig.RemoteAddr = netip.AddrPortFrom(ip, 0).String()
+				break
+			}
+		}
+	}
+
+	// dropping headers describing the original request, its body is never sent
+	for _, header := range append(originalMethodHeaders, originalURIHeaders...) {
+		orig.Header.Del(header)
+	}
+	orig.Body = http.NoBody
+	orig.ContentLength = 0
+	return orig, nil
+}
+
+// function to get client from values of an address header
+// proxies append to the list, so the last address not of a trusted proxy is the one they saw
+func (fa *ForwardAuth) clientIP(values []string) netip.Addr {
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return client
+		}
+		client = ip.Unmap()
+		if !fa.trusted(client) {
+			return client
+		}
+	}
+	return client
+}
+
+// function to check if an address is of a trusted proxy
+func (fa *ForwardAuth) trusted(ip netip.Addr) bool {
+	for _, prefix := range fa.proxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// function to get address of a peer without its port
+func remoteAddr(addr string) netip.Addr {
+	addrPort, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		ip, _ := netip.ParseAddr(addr)
+		return ip.Unmap()
+	}
+	return addrPort.Addr().Unmap()
+}
+
+// function to get value of the first header present
+func firstHeader(r *http.Request, headers []string) string {
+	for _, header := range headers {
+		if value := r.Header.Get(header); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+// forward_auth_test.go
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/router"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+func TestOriginalRequest(t *testing.T) {
+	fa := NewForwardAuth(router.NewRouter(), &utils.ForwardAuth{
+		TrustedProxies:  []string{"10.0.0.0/8", "192.168.1.1"},
+		ClientIPHeaders: []string{"X-Real-IP", "X-Forwarded-For"},
+		HostHeader:      "X-Forwarded-Host",
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		peer    string
+		headers map[string][]string
+		method  string
+		path    string
+		query   string
+		host    string
+		remote  string
+		invalid bool
+	}{
+		{
+			name:   "subrequest as is",
+			target: "/verify?x=1",
+			method: "GET", path: "/verify", query: "x=1", host: "example.com", remote: "10.0.0.5:1234",
+		},
+		{
+			name:   "traefik headers",
+			target: "/auth",
+			headers: map[string][]string{
+				"X-Forwarded-Method": {"post"},
+				"X-Forwarded-Uri":    {"/api/items?page=2"},
+				"X-Forwarded-Host":   {"shop.example.com"},
+			},
+			method: "POST", path: "/api/items", query: "page=2", host: "shop.example.com", remote: "10.0.0.5:1234",
+		},
+		{
+			name:   "nginx headers",
+			target: "/auth",
+			headers: map[string][]string{
+				"X-Original-Method": {"DELETE"},
+				"X-Original-Uri":    {"/api/items/7"},
+			},
+			method: "DELETE", path: "/api/items/7", host: "example.com", remote: "10.0.0.5:1234",
+		},
+		{
+			name:    "host header not configured is ignored",
+			target:  "/auth",
+			headers: map[string][]string{"X-Original-Host": {"evil.example.com"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "10.0.0.5:1234",
+		},
+		{
+			name:    "last untrusted hop",
+			target:  "/auth",
+			headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7", "10.1.2.3"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "198.51.100.7:0",
+		},
+		{
+			name:    "spoofed first hop",
+			target:  "/auth",
+			headers: map[string][]string{"X-Forwarded-For": {"1.1.1.1, 203.0.113.9, 192.168.1.1"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "203.0.113.9:0",
+		},
+		{
+			name:   "first configured header wins",
+			target: "/auth",
+			headers: map[string][]string{
+				"X-Real-Ip":       {"203.0.113.5"},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			method: "GET", path: "/auth", host: "example.com", remote: "203.0.113.5:0",
+		},
+		{
+			name:    "malformed hop stops the walk",
+			target:  "/auth",
+			headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9, unknown, 10.0.0.1"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "10.0.0.1:0",
+		},
+		{
+			name:    "all hops trusted",
+			target:  "/auth",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.2, 10.0.0.1"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "10.0.0.2:0",
+		},
+		{
+			name:    "untrusted peer can not name client",
+			target:  "/auth",
+			peer:    "192.0.2.1:1234",
+			headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9"}},
+			method:  "GET", path: "/auth", host: "example.com", remote: "192.0.2.1:1234",
+		},
+		{
+			name:    "relative uri",
+			target:  "/auth",
+			headers: map[string][]string{"X-Forwarded-Uri": {"api/items"}},
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			r.RemoteAddr = "10.0.0.5:1234"
+			if tt.peer != "" {
+				r.RemoteAddr = tt.peer
+			}
+			for name, values := range tt.headers {
+				r.Header[http.CanonicalHeaderKey(name)] = values
+			}
+
+			orig, err := fa.originalRequest(r)
+			if tt.invalid {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if orig.Method != tt.method {
+				t.Errorf("got method %s, want %s", orig.Method, tt.method)
+			}
+			if orig.URL.Path != tt.path || orig.URL.RawQuery != tt.query {
+				t.Errorf("got uri %s?%s, want %s?%s", orig.URL.Path, orig.URL.RawQuery, tt.path, tt.query)
+			}
+			if orig.Host != tt.host {
+				t.Errorf("got host %s, want %s", orig.Host, tt.host)
+			}
+			if orig.RemoteAddr != tt.remote {
+				t.Errorf("got remote %s, want %s", orig.RemoteAddr, tt.remote)
+			}
+			for _, header := range append(originalMethodHeaders, originalURIHeaders...) {
+				if orig.Header.Get(header) != "" {
+					t.Errorf("header %s not dropped", header)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardAuthUntrustedProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    int
+
+		// client the original request is taken from, spoofed in X-Forwarded-For
+		client string
+	}{
+		{name: "trusted", proxies: []string{"10.0.0.0/8"}, remote: "10.9.8.7:5000", want: http.StatusOK, client: "203.0.113.9:0"},
+		{name: "mapped v4", proxies: []string{"10.0.0.0/8"}, remote: "[::ffff:10.9.8.7]:5000", want: http.StatusOK, client: "203.0.113.9:0"},
+		{name: "untrusted", proxies: []string{"10.0.0.0/8"}, remote: "192.0.2.1:5000", want: http.StatusForbidden},
+		{name: "no proxies configured", remote: "192.0.2.1:5000", want: http.StatusOK, client: "192.0.2.1:5000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fa := NewForwardAuth(router.NewRouter(), &utils.ForwardAuth{
+				TrustedProxies:  tt.proxies,
+				ClientIPHeaders: []string{"X-Forwarded-For"},
+			})
+			r := httptest.NewRequest("GET", "/auth", nil)
+			r.RemoteAddr = tt.remote
+			r.Header.Set("X-Forwarded-For", "203.0.113.9")
+			w := httptest.NewRecorder()
+			fa.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d", w.Code, tt.want)
+			}
+			if tt.client == "" {
+				return
+			}
+			orig, err := fa.originalRequest(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if orig.RemoteAddr != tt.client {
+				t.Errorf("got client %s, want %s", orig.RemoteAddr, tt.client)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// grpc.go
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// grpc status of every http status a call can be rejected with
+var grpcCodes = map[int]codes.Code{
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusMethodNotAllowed:   codes.Unimplemented,
+	http.StatusTooManyRequests:    codes.ResourceExhausted,
+	http.StatusBadGateway:         codes.Unavailable,
+	http.StatusServiceUnavailable: codes.Unavailable,
+}
+
+// function to check if request is a grpc call
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// function to get full method of a grpc call, /package.Service/Method
+// taken from the original path as the route may rewrite it
+func grpcMethod(r *http.Request) string {
+	return originalPath(r)
+}
+
+// function to get keys of limits a grpc call is looked up by, most specific first
+func grpcRuleKeys(fullMethod string) []string {
+	keys := []string{fullMethod}
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 && i < len(fullMethod)-1 {
+		keys = append(keys, fullMethod[i+1:])
+	}
+	return append(keys, "*")
+}
+
+// function to reject a grpc call with a status, metadata already set on the response is sent as trailers
+func writeGRPCStatus(w http.ResponseWriter, status int, message string) {
+	code, exists := grpcCodes[status]
+	if !exists {
+		code = codes.Unknown
+	}
+
+	// collecting names first as trailers are added to the same map
+	header := w.Header()
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		values := header[name]
+		delete(header, name)
+		for _, value := range values {
+			header.Add(http.TrailerPrefix+name, value)
+		}
+	}
+
+	// grpc calls always succeed at http level, outcome is carried by the status trailer
+	header.Set("Content-Type", "application/grpc")
+	header.Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(code)))
+	header.Set(http.TrailerPrefix+"Grpc-Message", encodeGRPCMessage(message))
+	w.WriteHeader(http.StatusOK)
+}
+
+// function to percent encode a grpc status message as per grpc over http2
+func encodeGRPCMessage(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < ' ' || c > '~' || c == '%' {
+			sb.WriteString("%" + strings.ToUpper(strconv.FormatUint(uint64(c)|0x100, 16)[1:]))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+// grpc_test.go
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEncodeGRPCMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{name: "plain", message: "Too Many Requests", want: "Too Many Requests"},
+		{name: "empty", message: "", want: ""},
+		{name: "percent", message: "100% used", want: "100%25 used"},
+		{name: "newline", message: "a\nb", want: "a%0Ab"},
+		{name: "control", message: "\x00\x1f", want: "%00%1F"},
+		{name: "delete", message: "\x7f", want: "%7F"},
+		{name: "utf8 bytes", message: "é", want: "%C3%A9"},
+		{name: "printable edges", message: " ~", want: " ~"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeGRPCMessage(tt.message); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteGRPCStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   string
+	}{
+		{name: "throttled", status: http.StatusTooManyRequests, code: "8"},
+		{name: "unauthenticated", status: http.StatusUnauthorized, code: "16"},
+		{name: "forbidden", status: http.StatusForbidden, code: "7"},
+		{name: "unavailable", status: http.StatusServiceUnavailable, code: "14"},
+		{name: "unmapped", status: http.StatusTeapot, code: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set("Retry-After", "3")
+			w.Header().Add("X-Multi", "a")
+			w.Header().Add("X-Multi", "b")
+			writeGRPCStatus(w, tt.status, "no 100%")
+
+			res := w.Result()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("got status %d, want 200", res.StatusCode)
+			}
+			if got := res.Header.Get("Content-Type"); got != "application/grpc" {
+				t.Errorf("got content type %s", got)
+			}
+			if res.Header.Get("Retry-After") != "" {
+				t.Error("header not moved to trailers")
+			}
+			if got := res.Trailer.Get("Grpc-Status"); got != tt.code {
+				t.Errorf("got grpc status %s, want %s", got, tt.code)
+			}
+			if got := res.Trailer.Get("Grpc-Message"); got != "no 100%25" {
+				t.Errorf("got grpc message %s", got)
+			}
+			if got := res.Trailer.Get("Retry-After"); got != "3" {
+				t.Errorf("got retry after trailer %q, want 3", got)
+			}
+			if got := res.Trailer.Values("X-Multi"); len(got) != 2 {
+				t.Errorf("got trailer values %q, want both", got)
+			}
+		})
+	}
+}
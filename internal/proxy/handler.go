@@ -0,0 +1,515 @@
+// handler.go
+package proxy
+
+import (
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/access"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/auth"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/metrics"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// shortest time an unused partition of a limit is kept
+const minPartitionIdle = 5 * time.Minute
+
+// limit applied to requests of a method
+type rule struct {
+	// rate limit of the rule
+	rateLimit *utils.RateLimit
+
+	// limiter shared by all clients, nil if partitioned
+	limiter limiter.Limiter
+
+	// limiter per client key, nil if shared
+	partitioned *limiter.Partitioned
+
+	// source of client key
+	key string
+
+	// cost of requests, nil if every request costs one unit
+	cost *costRules
+
+	// limit is only evaluated, not enforced
+	dryRun bool
+}
+
+// constructor to initialize rule of a rate limit, state of its limiters is kept in rdb
+func newRule(rateLimit *utils.RateLimit, rdb *redis.Client) *rule {
+	algo, exists := limiter.Limiters[rateLimit.Strategy]
+	if !exists {
+		log.Fatalf("no such strategy %s", rateLimit.Strategy)
+	}
+
+	// function to initialize limiter of the rule, keyed by client key in redis
+	factory := func(key string) limiter.Limiter {
+		limit := *rateLimit
+		if limit.ID != "" {
+			limit.ID += ":" + key
+		}
+		l := algo(rdb, &limit)
+		// holding throttled requests instead of rejecting, never in dry run
+		if limit.Mode == "delay" && !limit.DryRun {
+			l = limiter.NewDelayed(l, &limit)
+		}
+		return l
+	}
+
+	rl := &rule{
+		rateLimit: rateLimit,
+		key:       rateLimit.Key,
+		dryRun:    rateLimit.DryRun,
+	}
+	if rateLimit.Cost != nil {
+		rl.cost = newCostRules(rateLimit.Cost)
+	}
+	if rl.key == "" {
+		rl.limiter = factory("-")
+	} else {
+		rl.partitioned = limiter.NewPartitioned(factory, max(minPartitionIdle, 2*rateLimit.TimeDuration), rateLimit.MaxKeys)
+	}
+	return rl
+}
+
+// function to get limiter applying to a request
+func (rl *rule) limiterFor(r *http.Request) limiter.Limiter {
+	if rl.partitioned == nil {
+		return rl.limiter
+	}
+	return rl.partitioned.For(rl.clientKey(r))
+}
+
+// function to get key of the client as limited by the rule, "-" if the limit is shared
+func (rl *rule) clientKey(r *http.Request) string {
+	if rl.partitioned == nil {
+		return "-"
+	}
+	return partitionKey(r, rl.key)
+}
+
+// function to stop limiters of the rule
+func (rl *rule) stop() {
+	if rl.partitioned != nil {
+		rl.partitioned.Stop()
+	} else {
+		rl.limiter.Stop()
+	}
+}
+
+// function to get client key of request as per source of the key
+func partitionKey(r *http.Request, source string) string {
+	source, name, _ := strings.Cut(source, ":")
+	key := ""
+	switch source {
+	case "ip":
+		key = ClientKey(r)
+	case "api_key":
+		if identity := auth.FromRequest(r); identity != nil {
+			key = identity.Subject
+		}
+	case "header":
+		key = r.Header.Get(name)
+	case "query":
+		key = r.URL.Query().Get(name)
+	case "claim":
+		if identity := auth.FromRequest(r); identity != nil {
+			key = auth.Claim(identity.Claims, name)
+		}
+	}
+
+	// requests without a key share a single partition
+	if key == "" {
+		return "-"
+	}
+	return key
+}
+
+// handler serving requests of a single resource
+type Handler struct {
+	// name of the resource
+	name string
+
+	// calls are grpc, limited per grpc method
+	grpc bool
+
+	// rules, key = http request method or grpc method
+	rules map[string]*rule
+
+	// rules replacing the default ones for a tier, key = tier then http request method
+	tiers map[string]map[string]*rule
+
+	// limits of long lived streams, nil if not configured
+	streams *streamLimits
+
+	// bandwidth limits, key = http request method or * for whole resource
+	bandwidths map[string]*bandwidthLimits
+
+	// proxy forwarding requests let through by dry run
+	proxy http.Handler
+
+	// global and resource allow and deny lists
+	global *access.Policy
+	policy *access.Policy
+
+	// bans of clients repeatedly throttled, nil if not configured
+	penalty *limiter.Penalty
+
+	// authentication methods of callers, first one with credentials present decides
+	authenticators []auth.Authenticator
+
+	// headers carrying forwarded claims, never taken from the client
+	claimHeaders []string
+
+	// response to throttled requests, nil for plain text 429
+	throttled *throttledResponse
+}
+
+// constructor to initialize handler of a resource forwarding to proxy, state shared by replicas is kept in rdb
+func NewHandler(resource *utils.Resource, proxy http.Handler, global *access.Policy, rdb *redis.Client) *Handler {
+	h := &Handler{
+		name:       resource.Name,
+		grpc:       resource.Protocol == "grpc",
+		rules:      make(map[string]*rule),
+		tiers:      make(map[string]map[string]*rule),
+		streams:    newStreamLimits(resource.Name, resource.Streaming, rdb),
+		bandwidths: make(map[string]*bandwidthLimits),
+		proxy:      proxy,
+		global:     global,
+		policy:     access.NewPolicy(resource.Access, rdb),
+		throttled:  newThrottledResponse(resource.ThrottledResponse),
+	}
+
+	// initializing authentication
+	if resource.Auth != nil && resource.Auth.JWT != nil {
+		h.authenticators = append(h.authenticators, auth.NewJWTAuthenticator(resource.Auth.JWT))
+		for _, header := range resource.Auth.JWT.ForwardClaims {
+			h.claimHeaders = append(h.claimHeaders, header)
+		}
+	}
+	if resource.Auth != nil && resource.Auth.APIKey != nil {
+		h.authenticators = append(h.authenticators, auth.NewAPIKeyAuthenticator(resource.Auth.APIKey, rdb))
+	}
+
+	// initializing penalty box shared by all replicas
+	if resource.Penalty != nil {
+		h.penalty = limiter.NewPenalty(rdb, "gogate:penalty:"+resource.Name, resource.Penalty)
+	}
+
+	// initializing bandwidth limits
+	if resource.Bandwidth != nil {
+		h.bandwidths["*"] = newBandwidthLimits(resource.Name, "*", resource.Bandwidth, rdb)
+	}
+
+	// initializing limiters
+	for method, rateLimit := range resource.RateLimits {
+		if rateLimit.Bandwidth != nil {
+			h.bandwidths[method] = newBandwidthLimits(resource.Name, method, rateLimit.Bandwidth, rdb)
+		}
+		h.rules[method] = newRule(rateLimit, rdb)
+	}
+	for tier, rateLimits := range resource.Tiers {
+		h.tiers[tier] = make(map[string]*rule)
+		for method, rateLimit := range rateLimits {
+			h.tiers[tier][method] = newRule(rateLimit, rdb)
+		}
+	}
+
+	return h
+}
+
+// function to get rule applying to a request, tier of the caller first
+func (h *Handler) ruleFor(r *http.Request) (*rule, bool) {
+	keys := h.ruleKeys(r)
+	if identity := auth.FromRequest(r); identity != nil && identity.Tier != "" {
+		for _, key := range keys {
+			if rl, exists := h.tiers[identity.Tier][key]; exists {
+				return rl, true
+			}
+		}
+	}
+	for _, key := range keys {
+		if rl, exists := h.rules[key]; exists {
+			return rl, true
+		}
+	}
+	return nil, false
+}
+
+// function to get keys of rules a request is looked up by, most specific first
+func (h *Handler) ruleKeys(r *http.Request) []string {
+	if h.grpc {
+		return grpcRuleKeys(grpcMethod(r))
+	}
+	return []string{r.Method}
+}
+
+// function to get method of a request as reported in metrics, key of the rule it matches
+// as requests without a rule would let clients add labels at will
+func (h *Handler) methodOf(r *http.Request) string {
+	for _, key := range h.ruleKeys(r) {
+		if _, exists := h.rules[key]; exists {
+			return key
+		}
+		for _, rules := range h.tiers {
+			if _, exists := rules[key]; exists {
+				return key
+			}
+		}
+	}
+	return "other"
+}
+
+// function to reject a request, grpc calls get a grpc status instead of an http error
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if h.grpc && isGRPC(r) {
+		writeGRPCStatus(w, status, strings.TrimPrefix(message, strconv.Itoa(status)+" "))
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// function to authenticate request by the first method whose credentials are present
+func (h *Handler) authenticate(r *http.Request) (*auth.Identity, error) {
+	for _, authenticator := range h.authenticators {
+		identity, err := authenticator.Authenticate(r)
+		if errors.Is(err, auth.ErrMissingCredentials) {
+			continue
+		}
+		return identity, err
+	}
+	return nil, auth.ErrMissingCredentials
+}
+
+// function to handle proxy request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, false)
+}
+
+// function to decide on a request of a fronting proxy without forwarding it, allowed requests get 200
+func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, true)
+}
+
+// function to decide on a request, forwarding it to destination unless only checked
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, checkOnly bool) {
+
+	// tracing the request
+	r, span := h.startSpan(r)
+	method := h.methodOf(r)
+
+	// recording the outcome of the request for the access log
+	aw := &accessWriter{ResponseWriter: w}
+	w = aw
+	entry := &accessEntry{
+		id:        utils.RequestID(r.Context()),
+		start:     time.Now(),
+		client:    ClientKey(r),
+		decision:  "allowed",
+		remaining: -1,
+		checked:   checkOnly,
+		w:         aw,
+	}
+	// requests not passing through the request id middleware
+	if entry.id == "" {
+		entry.id = uuid.NewString()
+		r = r.WithContext(utils.WithRequestID(r.Context(), entry.id))
+	}
+	defer func() {
+		endSpan(span, entry)
+		h.logAccess(r, entry)
+	}()
+
+	// dropping claim headers sent by the client, whatever identity the caller ends up with
+	for _, header := range h.claimHeaders {
+		r.Header.Del(header)
+	}
+
+	// rejecting unauthenticated callers before any limit
+	if len(h.authenticators) > 0 {
+		identity, err := h.authenticate(r)
+		if err != nil {
+			entry.decision = "unauthorized"
+			metrics.Requests.WithLabelValues(h.name, method, "unauthorized").Inc()
+			if errors.Is(err, auth.ErrMissingCredentials) {
+				h.reject(w, r, http.StatusUnauthorized, "401 Unauthorized: missing credentials")
+			} else {
+				h.reject(w, r, http.StatusUnauthorized, "401 Unauthorized: invalid credentials")
+			}
+			return
+		}
+		r = auth.WithIdentity(r, identity)
+
+		// forwarding claims to destination
+		for header, value := range identity.Headers {
+			if value != "" {
+				r.Header.Set(header, value)
+			}
+		}
+	}
+
+	// rejecting denied callers before telling them which methods exist
+	verdict := access.Decide(r, ClientKey(r), h.global, h.policy)
+	if verdict == access.Deny {
+		entry.decision = "denied"
+		metrics.Requests.WithLabelValues(h.name, method, "denied").Inc()
+		h.reject(w, r, http.StatusForbidden, "403 Forbidden")
+		return
+	}
+
+	// getting rule asper request method
+	rl, exists := h.ruleFor(r)
+	if !exists {
+		entry.decision = "method_not_allowed"
+		h.reject(w, r, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	// update headers to insure proper routing to the desired url
+	r.Header.Set("X-Forwarded-Host", r.Host)
+
+	// letting allowed callers bypass the limits
+	if verdict == access.Allow {
+		entry.decision = "allowlisted"
+		metrics.Requests.WithLabelValues(h.name, method, "allowlisted").Inc()
+		h.forward(w, r, entry)
+		return
+	}
+
+	// short circuiting banned clients before any limiter runs, keyed as the limiter of the rule
+	if h.penalty != nil {
+		if ban := h.penalty.Banned(rl.clientKey(r)); ban > 0 {
+			entry.decision = "banned"
+			metrics.Requests.WithLabelValues(h.name, method, "banned").Inc()
+			h.throttle(w, r, limitOf(rl.rateLimit), 0, ban)
+			return
+		}
+	}
+
+	// applying stream limits to websocket, sse and grpc streams
+	if h.streams != nil && isStream(r) && !checkOnly {
+		sw, closeStream, ok := h.streams.open(w, r)
+		if !ok {
+			entry.decision = "stream_throttled"
+			metrics.Requests.WithLabelValues(h.name, method, "stream_throttled").Inc()
+			h.throttle(w, r, h.streams.cfg.MaxConcurrent, 0, streamRetryAfter)
+			return
+		}
+		defer closeStream()
+		w = sw
+	}
+
+	// throttling bytes of request and response bodies, checked requests carry no body
+	for _, key := range h.ruleKeys(r) {
+		if bl, exists := h.bandwidths[key]; exists && key != "*" && !checkOnly {
+			w = bl.wrap(w, r)
+			break
+		}
+	}
+	if bl, exists := h.bandwidths["*"]; exists && !checkOnly {
+		w = bl.wrap(w, r)
+	}
+
+	// initializing new request, the propagated id is client supplied so limiters get their own
+	req := limiter.NewRequest(uuid.NewString())
+
+	// weighing the request as per its cost
+	if rl.cost != nil {
+		req.Cost = rl.cost.of(r)
+	}
+
+	// deciding admission of the request
+	decision := rl.limiterFor(r).Admit(r.Context(), req)
+	entry.remaining = decision.Remaining
+	if !decision.Allowed {
+
+		// client gave up while its request was held, it was not throttled
+		if r.Context().Err() != nil {
+			slog.InfoContext(r.Context(), "Skipping request: client disconnected")
+			entry.decision = "client_disconnected"
+			return
+		}
+
+		// forwarding anyway if limit is only evaluated
+		if rl.dryRun {
+			entry.decision = "dry_run_throttled"
+			metrics.Requests.WithLabelValues(h.name, method, "dry_run_throttled").Inc()
+			h.forward(w, r, entry)
+			return
+		}
+
+		entry.decision = "throttled"
+		metrics.Requests.WithLabelValues(h.name, method, "throttled").Inc()
+
+		// banning clients which keep exceeding the limit
+		if h.penalty != nil {
+			if ban := h.penalty.Strike(rl.clientKey(r)); ban > 0 {
+				slog.WarnContext(r.Context(), "Client banned", "client", rl.clientKey(r), "duration", ban)
+			}
+		}
+
+		// returning error due to too may requests, quota is back within a period at most
+		h.throttle(w, r, limitOf(rl.rateLimit), decision.Remaining, rl.rateLimit.TimeDuration)
+		return
+	}
+	metrics.Requests.WithLabelValues(h.name, method, "allowed").Inc()
+
+	// waiting in queue till admitted, limit only evaluated in dry run does not hold the request
+	if decision.Ticket != nil && !rl.dryRun {
+		if err := decision.Ticket.Wait(r.Context()); err != nil {
+			slog.InfoContext(r.Context(), "Skipping request: client disconnected")
+			entry.decision = "client_disconnected"
+			return
+		}
+	}
+
+	h.forward(w, r, entry)
+}
+
+// function to serve the request through proxy, checked requests are answered with identity and quota instead
+func (h *Handler) forward(w http.ResponseWriter, r *http.Request, entry *accessEntry) {
+	if !entry.checked {
+		// call to destination is traced by its transport
+		slog.DebugContext(r.Context(), "Forwarding request", "url", r.URL.String())
+		h.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	// claims are handed to the fronting proxy to be copied to the request
+	if identity := auth.FromRequest(r); identity != nil {
+		for header, value := range identity.Headers {
+			if value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+	}
+	if entry.remaining >= 0 {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(entry.remaining))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// function to stop all limiters
+func (h *Handler) Stop() {
+	for _, rl := range h.rules {
+		rl.stop()
+	}
+	for _, rules := range h.tiers {
+		for _, rl := range rules {
+			rl.stop()
+		}
+	}
+	for _, authenticator := range h.authenticators {
+		authenticator.Stop()
+	}
+	// global policy is stopped by its owner
+	h.policy.Stop()
+}
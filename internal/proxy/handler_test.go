@@ -0,0 +1,224 @@
+// handler_test.go
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// function to initialize handler of a resource on an in-memory redis, destination answers ok and the forwarded host
+func newTestHandler(t *testing.T, resource *utils.Resource) (*Handler, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	if err := limiter.Setup(context.Background(), rdb); err != nil {
+		t.Fatal(err)
+	}
+
+	for method, rateLimit := range resource.RateLimits {
+		rateLimit.ID = utils.LimitID(resource.Name, "-", method)
+		if err := rateLimit.Parse(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	destination := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Host", r.Header.Get("X-Forwarded-Host"))
+		w.Write([]byte("ok"))
+	})
+	h := NewHandler(resource, destination, nil, rdb)
+	t.Cleanup(h.Stop)
+	return h, mr
+}
+
+func TestHandlerClientDisconnected(t *testing.T) {
+	h, mr := newTestHandler(t, &utils.Resource{
+		Name: "api",
+		RateLimits: map[string]*utils.RateLimit{
+			"GET": {Strategy: "FIXED-WINDOW", Rate: "1/m", Key: "ip", Mode: "delay", MaxDelay: 200 * time.Millisecond},
+		},
+		Penalty: &utils.Penalty{Threshold: 1, Window: time.Minute, Ban: time.Minute, MaxBan: time.Hour, Memory: time.Hour},
+	})
+
+	// function to check whether the client has any strike or ban
+	struck := func() bool {
+		for _, key := range limiter.PenaltyKeys("gogate:penalty:api:192.0.2.1") {
+			if mr.Exists(key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// taking the only slot of the window
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "ok" {
+		t.Fatalf("got body %q, want ok", w.Body.String())
+	}
+
+	// client leaving while its request is held is neither answered nor struck
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if w.Body.Len() != 0 || w.Header().Get("Retry-After") != "" {
+		t.Errorf("got response %d %q to disconnected client", w.Code, w.Body.String())
+	}
+	if struck() {
+		t.Error("disconnected client struck")
+	}
+
+	// client still waiting at the deadline is throttled and struck
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want 429", w.Code)
+	}
+	if !struck() {
+		t.Error("throttled client not struck")
+	}
+}
+
+func TestHandlerPenaltyKey(t *testing.T) {
+	h, _ := newTestHandler(t, &utils.Resource{
+		Name: "api",
+		RateLimits: map[string]*utils.RateLimit{
+			"GET": {Strategy: "FIXED-WINDOW", Rate: "1/m", Key: "header:X-User"},
+		},
+		Penalty: &utils.Penalty{Threshold: 1, Window: time.Minute, Ban: time.Minute, MaxBan: time.Hour, Memory: time.Hour},
+	})
+
+	type request struct {
+		user   string
+		ip     string
+		status int
+	}
+	// bans follow the key of the limit rather than the ip of the client
+	for i, req := range []request{
+		{user: "alice", ip: "192.0.2.1", status: http.StatusOK},
+		{user: "alice", ip: "192.0.2.1", status: http.StatusTooManyRequests},
+		{user: "alice", ip: "192.0.2.2", status: http.StatusTooManyRequests},
+		{user: "bob", ip: "192.0.2.1", status: http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = req.ip + ":1234"
+		r.Header.Set("X-User", req.user)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != req.status {
+			t.Errorf("request %d: got status %d, want %d", i+1, w.Code, req.status)
+		}
+	}
+}
+
+func TestHandlerForwardedHost(t *testing.T) {
+	h, _ := newTestHandler(t, &utils.Resource{
+		Name:       "api",
+		RateLimits: map[string]*utils.RateLimit{"GET": {Strategy: "FIXED-WINDOW", Rate: "10/s"}},
+	})
+
+	r := httptest.NewRequest("GET", "http://shop.example.com/items", nil)
+	r.Header.Set("X-Forwarded-Host", "evil.example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("X-Seen-Host"); got != "shop.example.com" {
+		t.Errorf("got forwarded host %q, want shop.example.com", got)
+	}
+}
+
+func TestHandlerTooManyStreams(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource *utils.Resource
+		method   string
+		path     string
+		header   map[string]string
+		grpc     bool
+	}{
+		{
+			name: "server sent events",
+			resource: &utils.Resource{
+				Name:       "events",
+				RateLimits: map[string]*utils.RateLimit{"GET": {Strategy: "FIXED-WINDOW", Rate: "10/s"}},
+				Streaming:  &utils.Streaming{MaxConcurrent: 1},
+			},
+			method: "GET", path: "/events",
+			header: map[string]string{"Accept": "text/event-stream"},
+		},
+		{
+			name: "grpc",
+			resource: &utils.Resource{
+				Name:       "svc",
+				Protocol:   "grpc",
+				RateLimits: map[string]*utils.RateLimit{"*": {Strategy: "FIXED-WINDOW", Rate: "10/s"}},
+				Streaming:  &utils.Streaming{MaxConcurrent: 1},
+			},
+			method: "POST", path: "/pkg.Svc/Watch",
+			header: map[string]string{"Content-Type": "application/grpc"},
+			grpc:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandler(t, tt.resource)
+
+			// function to get a request opening a stream
+			newRequest := func(ctx context.Context) *http.Request {
+				r := httptest.NewRequest(tt.method, tt.path, nil).WithContext(ctx)
+				if tt.grpc {
+					r.ProtoMajor = 2
+				}
+				for name, value := range tt.header {
+					r.Header.Set(name, value)
+				}
+				return r
+			}
+
+			// holding the only slot with a stream open till the test ends
+			opened := make(chan struct{})
+			h.proxy = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(opened)
+				<-r.Context().Done()
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h.ServeHTTP(httptest.NewRecorder(), newRequest(ctx))
+			}()
+			<-opened
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, newRequest(context.Background()))
+			res := w.Result()
+			if tt.grpc {
+				if got := res.Trailer.Get("Grpc-Status"); got != "8" {
+					t.Errorf("got grpc status %q, want 8", got)
+				}
+				return
+			}
+			if res.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("got status %d, want 429", res.StatusCode)
+			}
+			if got := res.Header.Get("Retry-After"); got != "1" {
+				t.Errorf("got retry after %q, want 1", got)
+			}
+			if got := res.Header.Get("X-RateLimit-Limit"); got != "1" {
+				t.Errorf("got limit %q, want 1", got)
+			}
+		})
+	}
+}
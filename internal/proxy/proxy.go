@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -14,125 +16,240 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Sp92535/GoGate-RateLimiter/internal/access"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/admin"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/balancer"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/decision"
 	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/router"
 	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
-	"github.com/google/uuid"
+	"google.golang.org/grpc"
 )
 
 // function to initialize new reverse proxy for a target url
 func NewReverseProxy(target *url.URL) *httputil.ReverseProxy {
-	return httputil.NewSingleHostReverseProxy(target)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	// update host to insure proper routing to the desired url
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+	}
+	return proxy
 }
 
-// function to handle proxy request
-func ProxyRequestHandler(proxy *httputil.ReverseProxy, url *url.URL, endpoint string, limiters map[string]limiter.Limiter) func(http.ResponseWriter, *http.Request) {
+// function to initialize pool of reverse proxies for all destinations of a resource
+func NewPool(resource *utils.Resource) *balancer.Pool {
+	// all destinations of a resource share the transport
+	transport := NewTransport(&resource.Transport)
 
-	// return function expected by http handler
-	return func(w http.ResponseWriter, r *http.Request) {
-		// getting algo asper request method
-		algo, exists := limiters[r.Method]
-		if !exists {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			return
+	var dests []*balancer.Destination
+	for _, destination := range resource.Destinations {
+		// parsing the target url
+		url, err := url.Parse(destination.URL)
+		if err != nil {
+			log.Fatalf("Invalid URL: %v", err)
 		}
-
-		log.Printf("Request recieved at %s\n", endpoint)
-
-		// update headers to insure proper routing to the desired url
-		r.URL.Host = url.Host
-		r.URL.Scheme = url.Scheme
-		r.Header.Set("X-Forwarded-Host", r.Header.Get("Host"))
-		r.Host = url.Host
-
-		// trimming the redundant endpoint
-		path := r.URL.Path
-		r.URL.Path = strings.TrimPrefix(path, endpoint)
-
-		// initializing new request
-		req := limiter.NewRequest(uuid.NewString(), w, r)
-
-		// attempting to add new request in queue
-		if !algo.AddRequest(req) {
-			log.Printf("Request Throttled")
-			// returning error due to too may requests
-			http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
-			return
+		proxy := NewReverseProxy(url)
+		proxy.Transport = &tracingTransport{transport}
+		dest := balancer.NewDestination(url, destination.Weight, proxy)
+		dest.Transport = transport
+
+		// reporting outcome of every request for passive health checks
+		proxy.ModifyResponse = func(res *http.Response) error {
+			if res.StatusCode >= 500 {
+				dest.ReportFailure()
+			} else {
+				dest.ReportSuccess()
+			}
+			return nil
+		}
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.ErrorContext(r.Context(), "Error reaching destination", "destination", url.String(), "error", err)
+			dest.ReportFailure()
+			if isGRPC(r) {
+				writeGRPCStatus(w, http.StatusBadGateway, "destination unavailable")
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
 		}
 
-		// waiting for closure of connection
-		select {
-
-		// connection closed by server
-		case <-req.Ctx.Done():
-			return
+		dests = append(dests, dest)
+	}
+	return balancer.NewPool(resource.Name, resource.Balancer, dests, ClientKey, &resource.HealthCheck)
+}
 
-		// connection closed by client
-		case <-r.Context().Done():
-			log.Println("Client disconnected while waiting in queue")
-			return
+// function to get the key identifying the client of a request
+func ClientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
 
-		}
+// function to get path of a request as sent by the client, before the route rewrites it
+func originalPath(r *http.Request) string {
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	if path == "" {
+		path = r.URL.Path
 	}
+	return path
 }
 
 // function to initialize and run all proxies
-func Run() {
+func Run(configPath string) {
 
 	// load config from yaml
-	config := utils.NewConfiguration("config/config.yaml")
+	config := utils.NewConfiguration(configPath)
+
+	// structured logs as per config
+	utils.InitLogger(&config.Logging)
+
+	// traces exported to collector if configured
+	shutdownTracer := utils.InitTracer(&config.Tracing)
+
+	// initializing redis client and scripts shared by all limiters
+	rdb := utils.InitRedis(&config.Redis)
+	defer rdb.Close()
+	if err := limiter.Setup(context.Background(), rdb); err != nil {
+		// scripts are sent in full on first use if redis comes up later
+		log.Printf("Error preloading scripts: %v", err)
+	}
 
 	// initializing a new router
-	mux := http.NewServeMux()
+	rtr := router.NewRouter()
 
 	// struturing the server address
 	address := config.Server.Host + ":" + config.Server.Port
 
 	// initializing server
 	srv := http.Server{
-		Addr:    address,
-		Handler: mux,
+		Addr:      address,
+		Handler:   WithRequestID(config.Server.RequestIDHeader, rtr),
+		Protocols: new(http.Protocols),
+	}
+
+	// http2 is enabled unless disabled, also over plain text with prior knowledge for grpc clients
+	http2 := config.Server.HTTP2 == nil || *config.Server.HTTP2
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(http2)
+	srv.Protocols.SetUnencryptedHTTP2(http2)
+
+	// context for closure of background tasks of the server
+	srvCtx, srvCancel := context.WithCancel(context.Background())
+	defer srvCancel()
+
+	// terminating tls if configured
+	if config.Server.TLS != nil {
+		srv.TLSConfig = NewTLSConfig(srvCtx, config.Server.TLS)
 	}
 
 	// stop funcs to stop every thing at end
 	var stopFunc []func()
 
-	// looping through all the endpoints to set proxies
-	for _, resource := range config.Resources {
+	// all pools to be reported by admin api
+	var pools []*balancer.Pool
 
-		// parsing the target url
-		url, err := url.Parse(resource.DestinationURL)
-		if err != nil {
-			log.Fatalf("Invalid URL: %v", err)
-		}
+	// allow and deny lists of all resources
+	global := access.NewPolicy(config.Access, rdb)
+	stopFunc = append(stopFunc, global.Stop)
 
-		// creating a new reverse proxy
-		proxy := NewReverseProxy(url)
+	// looping through all the endpoints to set proxies
+	for i, resource := range config.Resources {
 
-		// initializing limiters
-		limiters := make(map[string]limiter.Limiter)
-		for method, rateLimit := range resource.RateLimits {
-			algo, exists := limiter.Limiters[rateLimit.Strategy]
-			if !exists {
-				log.Fatalf("no such strategy %s", rateLimit.Strategy)
-			}
-			limiters[method] = algo(rateLimit, proxy)
-			// exiting all algos at end of Run
-			stopFunc = append(stopFunc, limiters[method].Stop)
-		}
+		// creating a new pool of reverse proxies
+		proxy := NewPool(&resource)
+		pools = append(pools, proxy)
+		stopFunc = append(stopFunc, proxy.Stop)
 
 		// handling the proxy
-		mux.HandleFunc(resource.Endpoint, ProxyRequestHandler(proxy, url, resource.Endpoint, limiters))
+		handler := NewHandler(&resource, proxy, global, rdb)
+		stopFunc = append(stopFunc, handler.Stop)
+		route, err := router.NewRoute(&resource, i, handler)
+		if err != nil {
+			log.Fatalf("invalid route of resource %s: %v", resource.Name, err)
+		}
+		rtr.Add(route)
 	}
 
 	log.Printf("Server started at %s", address)
 
 	// starting the server as a separate go routine
 	go func() {
-		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if srv.TLSConfig != nil {
+			// certificates are served by tls config
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("unable to start server %v", err)
 		}
 	}()
 
+	// starting the admin api if configured
+	var admSrv *http.Server
+	if config.Admin.Port != "" {
+		admSrv = &http.Server{
+			Addr:    config.Admin.Host + ":" + config.Admin.Port,
+			Handler: admin.NewHandler(pools),
+		}
+		log.Printf("Admin API started at %s", admSrv.Addr)
+		go func() {
+			if err := admSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("unable to start admin server %v", err)
+			}
+		}()
+	}
+
+	// starting the forward auth endpoint if configured
+	var authSrv *http.Server
+	if config.ForwardAuth.Port != "" {
+		authSrv = &http.Server{
+			Addr:    config.ForwardAuth.Host + ":" + config.ForwardAuth.Port,
+			Handler: WithRequestID(config.Server.RequestIDHeader, NewForwardAuth(rtr, &config.ForwardAuth)),
+		}
+		log.Printf("Forward auth started at %s", authSrv.Addr)
+		go func() {
+			if err := authSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("unable to start forward auth server %v", err)
+			}
+		}()
+	}
+
+	// starting the decision service if configured
+	var decisionSrv *grpc.Server
+	var checkSrv *http.Server
+	if config.Decision.GRPCPort != "" || config.Decision.HTTPPort != "" {
+		service := decision.NewService(&config.Decision, rdb)
+		stopFunc = append(stopFunc, service.Stop)
+
+		if config.Decision.GRPCPort != "" {
+			lis, err := net.Listen("tcp", config.Decision.Host+":"+config.Decision.GRPCPort)
+			if err != nil {
+				log.Fatalf("unable to start decision service %v", err)
+			}
+			decisionSrv = decision.NewGRPCServer(service)
+			log.Printf("Decision service started at %s", lis.Addr())
+			go decisionSrv.Serve(lis)
+		}
+		if config.Decision.HTTPPort != "" {
+			checkSrv = &http.Server{
+				Addr:    config.Decision.Host + ":" + config.Decision.HTTPPort,
+				Handler: WithRequestID(config.Server.RequestIDHeader, decision.NewHTTPHandler(service)),
+			}
+			log.Printf("Check API started at %s", checkSrv.Addr)
+			go func() {
+				if err := checkSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("unable to start check api %v", err)
+				}
+			}()
+		}
+	}
+
 	// graceful shutdown
 	// initializing an buffered channel to listen for shutdown signal CTRL+C
 	sigChan := make(chan os.Signal, 1)
@@ -152,6 +269,23 @@ func Run() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("HTTP shutdown error: %v", err)
 	}
+	if admSrv != nil {
+		admSrv.Shutdown(shutdownCtx)
+	}
+	if authSrv != nil {
+		authSrv.Shutdown(shutdownCtx)
+	}
+	if checkSrv != nil {
+		checkSrv.Shutdown(shutdownCtx)
+	}
+	if decisionSrv != nil {
+		decisionSrv.GracefulStop()
+	}
+
+	// flushing pending spans
+	if err := shutdownTracer(shutdownCtx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
 
 	log.Println("Graceful shutdown complete.")
 
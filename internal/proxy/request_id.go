@@ -0,0 +1,82 @@
+// request_id.go
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
+)
+
+// longest request id accepted from callers
+const maxRequestIDLength = 128
+
+// function to assign id to every request, forwarded to the destination and echoed to the caller
+func WithRequestID(header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// honoring id sent by the caller unless malformed
+		id := r.Header.Get(header)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		r.Header.Set(header, id)
+		r = r.WithContext(utils.WithRequestID(r.Context(), id))
+		next.ServeHTTP(&requestIDWriter{ResponseWriter: w, header: header, id: id}, r)
+	})
+}
+
+// function to check if request id is non empty printable ascii of bounded length
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// response writer setting the request id just before headers are sent
+// so the one echoed by the destination does not appear twice
+type requestIDWriter struct {
+	http.ResponseWriter
+
+	header string
+	id     string
+}
+
+// function to set request id header
+func (rw *requestIDWriter) setID() {
+	rw.Header().Set(rw.header, rw.id)
+}
+
+func (rw *requestIDWriter) WriteHeader(status int) {
+	rw.setID()
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *requestIDWriter) Write(p []byte) (int, error) {
+	rw.setID()
+	return rw.ResponseWriter.Write(p)
+}
+
+// function to flush streaming responses
+func (rw *requestIDWriter) Flush() {
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// function to take over connection of upgraded requests, response is written by the proxy
+func (rw *requestIDWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.setID()
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// function to expose underlying writer to response controller
+func (rw *requestIDWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,80 @@
+// request_id_test.go
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: "0f8fad5b-d9cb-469f-a165-70867728950e", want: true},
+		{name: "printable", id: "req_42:a/b~", want: true},
+		{name: "longest", id: strings.Repeat("a", maxRequestIDLength), want: true},
+		{name: "empty", id: "", want: false},
+		{name: "too long", id: strings.Repeat("a", maxRequestIDLength+1), want: false},
+		{name: "space", id: "a b", want: false},
+		{name: "newline", id: "a\nX-Injected: 1", want: false},
+		{name: "control", id: "a\x00", want: false},
+		{name: "non ascii", id: "ïd", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validRequestID(tt.id); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{name: "honored", sent: "abc-123", keep: true},
+		{name: "missing", sent: "", keep: false},
+		{name: "malformed", sent: "a b", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen, fromCtx string
+			handler := WithRequestID("X-Request-ID", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Header.Get("X-Request-ID")
+				fromCtx = utils.RequestID(r.Context())
+				// echoed by the destination, must not appear twice
+				w.Header().Add("X-Request-ID", seen)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.sent != "" {
+				r.Header.Set("X-Request-ID", tt.sent)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.keep && seen != tt.sent {
+				t.Errorf("got id %q, want %q", seen, tt.sent)
+			}
+			if !tt.keep && (seen == tt.sent || !validRequestID(seen)) {
+				t.Errorf("got id %q, want a generated one", seen)
+			}
+			if fromCtx != seen {
+				t.Errorf("got id %q in context, want %q", fromCtx, seen)
+			}
+			if got := w.Result().Header.Values("X-Request-ID"); len(got) != 1 || got[0] != seen {
+				t.Errorf("got response ids %q, want [%s]", got, seen)
+			}
+		})
+	}
+}
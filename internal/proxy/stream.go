@@ -0,0 +1,246 @@
+// stream.go
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// time after which a client with too many streams is told to retry, a slot frees up as soon as one of its streams closes
+const streamRetryAfter = time.Second
+
+// error returned to the proxy when a websocket session exceeds its message limit
+var errMessageLimit = errors.New("websocket message limit exceeded")
+
+// limits applied to long lived streams of a resource
+type streamLimits struct {
+	// name of the resource
+	name string
+
+	// redis client holding slots, buckets and message limits of streams
+	rdb *redis.Client
+
+	cfg *utils.Streaming
+}
+
+// constructor to initialize stream limits
+func newStreamLimits(name string, cfg *utils.Streaming, rdb *redis.Client) *streamLimits {
+	if cfg == nil {
+		return nil
+	}
+	if msg := cfg.MessageLimit; msg != nil {
+		if _, exists := limiter.Limiters[msg.Strategy]; !exists {
+			log.Fatalf("no such strategy %s", msg.Strategy)
+		}
+	}
+	return &streamLimits{
+		name: name,
+		rdb:  rdb,
+		cfg:  cfg,
+	}
+}
+
+// function to check if request opens a websocket
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// function to check if request opens a long lived stream
+func isStream(r *http.Request) bool {
+	return isWebSocket(r) ||
+		strings.HasPrefix(r.Header.Get("Accept"), "text/event-stream") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// function to open a stream, returned function closes it
+// returns false if client already has too many streams open
+func (sl *streamLimits) open(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func(), bool) {
+	id := uuid.NewString()
+	closers := []func(){}
+
+	// taking a slot shared by all replicas
+	if sl.cfg.MaxConcurrent > 0 {
+		concurrency := limiter.NewConcurrency(sl.rdb, "gogate:streams:"+sl.name+":"+ClientKey(r), sl.cfg.MaxConcurrent)
+		release, ok := concurrency.Acquire(id)
+		if !ok {
+			return nil, nil, false
+		}
+		closers = append(closers, release)
+	}
+
+	// throttling bytes of the response, bucket of the stream is dropped once closed
+	if sl.cfg.BytesPerSecond > 0 {
+		key := "gogate:bandwidth:stream:" + id
+		w = newThrottledWriter(w, r.Context(), limiter.NewBandwidth(sl.rdb, key, sl.cfg.BytesPerSecond, 0))
+		closers = append(closers, func() {
+			if err := sl.rdb.Del(context.Background(), key).Err(); err != nil {
+				slog.ErrorContext(r.Context(), "Error deleting stream bandwidth", "error", err)
+			}
+		})
+	}
+
+	sw := &streamWriter{ResponseWriter: w, ctx: r.Context()}
+
+	// limiting messages of websocket session, limit of the session is dropped once closed
+	if msg := sl.cfg.MessageLimit; msg != nil && isWebSocket(r) {
+		limit := *msg
+		limit.ID = "gogate:streams:" + sl.name + ":msg:" + id
+		algo := limiter.Limiters[msg.Strategy](sl.rdb, &limit)
+		closers = append(closers, func() {
+			algo.Stop()
+			if err := limiter.Delete(context.Background(), sl.rdb, limit.ID); err != nil {
+				slog.ErrorContext(r.Context(), "Error deleting stream message limit", "error", err)
+			}
+		})
+		sw.allow = func() bool {
+			// every message passes through the strategy like a request
+			decision := algo.Admit(r.Context(), limiter.NewRequest(uuid.NewString()))
+			if !decision.Allowed {
+				return false
+			}
+			// holding the message till admitted by queue
+			if decision.Ticket != nil {
+				return decision.Ticket.Wait(r.Context()) == nil
+			}
+			return true
+		}
+	}
+
+	return sw, func() {
+		for _, closer := range closers {
+			closer()
+		}
+	}, true
+}
+
+// response writer applying stream limits
+type streamWriter struct {
+	http.ResponseWriter
+
+	// function to permit websocket message, nil if unlimited
+	allow func() bool
+
+	// context of the request for logging
+	ctx context.Context
+}
+
+// function to flush streamed data to client
+func (sw *streamWriter) Flush() {
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// function to get underlying response writer
+func (sw *streamWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// function to take over the connection of an upgraded websocket
+func (sw *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(sw.ResponseWriter).Hijack()
+	if err != nil || sw.allow == nil {
+		return conn, brw, err
+	}
+	return &wsConn{Conn: conn, allow: sw.allow, ctx: sw.ctx}, brw, nil
+}
+
+// client connection counting websocket messages sent to the destination
+type wsConn struct {
+	net.Conn
+
+	// context of the request for logging
+	ctx context.Context
+
+	// function to permit a new message
+	allow func() bool
+
+	// partially read frame header
+	header []byte
+
+	// payload bytes left in current frame
+	remaining uint64
+}
+
+// function to read from client and check every new message
+func (c *wsConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.scan(p[:n]) {
+		slog.WarnContext(c.ctx, "Websocket session throttled")
+		c.Conn.Close()
+		return 0, errMessageLimit
+	}
+	return n, err
+}
+
+// function to walk through frames in data, false if a message is not permitted
+func (c *wsConn) scan(data []byte) bool {
+	for len(data) > 0 {
+
+		// skipping payload of current frame
+		if c.remaining > 0 {
+			skip := min(uint64(len(data)), c.remaining)
+			c.remaining -= skip
+			data = data[skip:]
+			continue
+		}
+
+		// collecting header of next frame
+		c.header = append(c.header, data[0])
+		data = data[1:]
+		size, payload, ok := parseFrameHeader(c.header)
+		if !ok || len(c.header) < size {
+			continue
+		}
+
+		// text and binary frames start a new message, control and continuation frames do not
+		opcode := c.header[0] & 0x0f
+		c.header = c.header[:0]
+		c.remaining = payload
+		if (opcode == 0x1 || opcode == 0x2) && !c.allow() {
+			return false
+		}
+	}
+	return true
+}
+
+// function to get size of a websocket frame header and its payload length
+// returns false till enough bytes are available to know the size
+func parseFrameHeader(header []byte) (int, uint64, bool) {
+	if len(header) < 2 {
+		return 0, 0, false
+	}
+	size := 2
+	if header[1]&0x80 != 0 {
+		// masking key
+		size += 4
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		size += 2
+		if len(header) < 4 {
+			return 0, 0, false
+		}
+		length = uint64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		size += 8
+		if len(header) < 10 {
+			return 0, 0, false
+		}
+		length = binary.BigEndian.Uint64(header[2:10])
+	}
+	return size, length, true
+}
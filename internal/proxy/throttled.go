@@ -0,0 +1,174 @@
+// throttled.go
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"log"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// fields of a throttled request available to the body
+type throttleData struct {
+	// status text of the response
+	Error string `json:"error"`
+
+	// units the limit allows, 0 if unknown
+	Limit int `json:"limit"`
+
+	// units left in the limit
+	Remaining int `json:"remaining"`
+
+	// unix time by which the limit has quota again
+	Reset int64 `json:"reset"`
+
+	// seconds after which the request may be retried
+	RetryAfter int `json:"retry_after"`
+
+	Resource  string `json:"-"`
+	Method    string `json:"-"`
+	Path      string `json:"-"`
+	RequestID string `json:"-"`
+}
+
+// functions available to body templates, json quotes a value for json bodies
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// response written to throttled requests of a resource
+type throttledResponse struct {
+	status      int
+	contentType string
+
+	// body template, nil if fields are written as json
+	body interface {
+		Execute(w io.Writer, data any) error
+	}
+
+	// headers added to every throttled response
+	headers map[string]string
+}
+
+// constructor to initialize throttled response, nil if the default plain text 429 is used
+func newThrottledResponse(cfg *utils.ThrottledResponse) *throttledResponse {
+	if cfg == nil {
+		return nil
+	}
+	tr := &throttledResponse{
+		status:      cfg.Status,
+		contentType: cfg.ContentType,
+		headers:     cfg.Headers,
+	}
+	text := cfg.Body
+	if text == "" && !strings.Contains(cfg.ContentType, "json") {
+		text = "{{.Error}}\n"
+	}
+	switch {
+	case text == "":
+	case strings.Contains(cfg.ContentType, "html"):
+		// path and request id come from the client, so html bodies are escaped
+		body, err := htmltemplate.New("throttled").Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+		if err != nil {
+			log.Fatalf("invalid throttled body %v", err)
+		}
+		tr.body = body
+	default:
+		body, err := template.New("throttled").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			log.Fatalf("invalid throttled body %v", err)
+		}
+		tr.body = body
+	}
+	return tr
+}
+
+// function to get no of units a rate limit allows
+func limitOf(rateLimit *utils.RateLimit) int {
+	if rateLimit.Strategy == "TOKEN-BUCKET" || rateLimit.Strategy == "LEAKY-BUCKET" {
+		return rateLimit.Capacity
+	}
+	return rateLimit.NoOfRequests
+}
+
+// function to answer a throttled or banned request with quota headers and the configured response
+func (h *Handler) throttle(w http.ResponseWriter, r *http.Request, limit int, remaining int, retryAfter time.Duration) {
+	status := http.StatusTooManyRequests
+	if h.throttled != nil {
+		status = h.throttled.status
+	}
+	data := throttleData{
+		Error:      http.StatusText(status),
+		Limit:      limit,
+		Remaining:  max(remaining, 0),
+		RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+		Resource:   h.name,
+		Method:     r.Method,
+		Path:       originalPath(r),
+		RequestID:  utils.RequestID(r.Context()),
+	}
+	if h.grpc {
+		data.Method = grpcMethod(r)
+	}
+	data.Reset = time.Now().Add(time.Duration(data.RetryAfter) * time.Second).Unix()
+
+	// quota headers, sent as trailer metadata to grpc callers
+	header := w.Header()
+	header.Set("Retry-After", strconv.Itoa(data.RetryAfter))
+	if limit > 0 {
+		header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	}
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(data.Remaining))
+
+	tr := h.throttled
+	if tr == nil {
+		h.reject(w, r, http.StatusTooManyRequests, "429 Too Many Requests")
+		return
+	}
+	for name, value := range tr.headers {
+		header.Set(name, value)
+	}
+
+	// grpc callers only get a status, throttling is resource exhausted whatever the http status
+	if h.grpc && isGRPC(r) {
+		writeGRPCStatus(w, http.StatusTooManyRequests, http.StatusText(tr.status))
+		return
+	}
+
+	body, err := tr.render(&data)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error rendering throttled response", "error", err)
+		h.reject(w, r, tr.status, strconv.Itoa(tr.status)+" "+http.StatusText(tr.status))
+		return
+	}
+	header.Set("Content-Type", tr.contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(tr.status)
+	w.Write(body)
+}
+
+// function to render body of a throttled response
+func (tr *throttledResponse) render(data *throttleData) ([]byte, error) {
+	if tr.body == nil {
+		body, err := json.Marshal(data)
+		return append(body, '\n'), err
+	}
+	var buf bytes.Buffer
+	if err := tr.body.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+// throttled_test.go
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+func TestThrottledRender(t *testing.T) {
+	data := throttleData{
+		Error:      "Too Many Requests",
+		Limit:      10,
+		Remaining:  0,
+		Reset:      1700000000,
+		RetryAfter: 3,
+		Resource:   "api",
+		Method:     "GET",
+		Path:       `/x/<script>"`,
+		RequestID:  "req-1",
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "plain default",
+			contentType: "text/plain; charset=utf-8",
+			want:        "Too Many Requests\n",
+		},
+		{
+			name:        "json default",
+			contentType: "application/json",
+			want:        `{"error":"Too Many Requests","limit":10,"remaining":0,"reset":1700000000,"retry_after":3}` + "\n",
+		},
+		{
+			name:        "json template quotes",
+			contentType: "application/json",
+			body:        `{"path":{{json .Path}},"id":{{json .RequestID}}}`,
+			want:        `{"path":"/x/\u003cscript\u003e\"","id":"req-1"}`,
+		},
+		{
+			name:        "plain template",
+			contentType: "text/plain",
+			body:        "{{.Resource}} {{.Method}} {{.Path}} retry in {{.RetryAfter}}s",
+			want:        `api GET /x/<script>" retry in 3s`,
+		},
+		{
+			name:        "html escaped",
+			contentType: "text/html",
+			body:        "<p>{{.Path}}</p>",
+			want:        "<p>/x/&lt;script&gt;&#34;</p>",
+		},
+		{
+			name:        "html default",
+			contentType: "text/html; charset=utf-8",
+			want:        "Too Many Requests\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newThrottledResponse(&utils.ThrottledResponse{Status: 429, ContentType: tt.contentType, Body: tt.body})
+			got, err := tr.render(&data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	tests := []struct {
+		name      string
+		throttled *utils.ThrottledResponse
+		grpc      bool
+		status    int
+		body      string
+	}{
+		{name: "default", status: http.StatusTooManyRequests, body: "429 Too Many Requests\n"},
+		{
+			name:      "configured",
+			throttled: &utils.ThrottledResponse{Status: 503, ContentType: "application/json", Headers: map[string]string{"X-Reason": "quota"}},
+			status:    http.StatusServiceUnavailable,
+		},
+		{
+			name:      "grpc ignores status",
+			throttled: &utils.ThrottledResponse{Status: 503, ContentType: "application/json"},
+			grpc:      true,
+			status:    http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{name: "api", grpc: tt.grpc, throttled: newThrottledResponse(tt.throttled)}
+			r := httptest.NewRequest("POST", "/pkg.Svc/Call", nil)
+			if tt.grpc {
+				r.ProtoMajor = 2
+				r.Header.Set("Content-Type", "application/grpc")
+			}
+			w := httptest.NewRecorder()
+			h.throttle(w, r, 10, -1, 2500*time.Millisecond)
+
+			res := w.Result()
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", res.StatusCode, tt.status)
+			}
+			if tt.grpc {
+				if got := res.Trailer.Get("Grpc-Status"); got != "8" {
+					t.Errorf("got grpc status %s, want 8", got)
+				}
+				if got := res.Trailer.Get("Retry-After"); got != "3" {
+					t.Errorf("got retry after trailer %q, want 3", got)
+				}
+				return
+			}
+			if got := res.Header.Get("Retry-After"); got != "3" {
+				t.Errorf("got retry after %q, want 3", got)
+			}
+			if got := res.Header.Get("X-RateLimit-Limit"); got != "10" {
+				t.Errorf("got limit %q, want 10", got)
+			}
+			if got := res.Header.Get("X-RateLimit-Remaining"); got != "0" {
+				t.Errorf("got remaining %q, want 0", got)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.body)
+			}
+			if tt.throttled == nil {
+				return
+			}
+			if got := res.Header.Get("X-Reason"); got != "quota" {
+				t.Errorf("got configured header %q", got)
+			}
+			var data throttleData
+			if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+				t.Fatal(err)
+			}
+			if data.Error != "Service Unavailable" || data.RetryAfter != 3 || data.Limit != 10 {
+				t.Errorf("got body %s", strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
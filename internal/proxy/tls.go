@@ -0,0 +1,178 @@
+// tls.go
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// all certificates served by the listener
+type certStore struct {
+	// certificate files
+	files []utils.Certificate
+
+	// last seen modification time of every file
+	modTimes map[string]time.Time
+
+	// parsed certificates
+	certs []tls.Certificate
+	mu    sync.RWMutex
+}
+
+// constructor to initialize certificate store
+func newCertStore(files []utils.Certificate) *certStore {
+	cs := &certStore{
+		files:    files,
+		modTimes: make(map[string]time.Time),
+	}
+	if err := cs.load(); err != nil {
+		log.Fatalf("unable to load certificates %v", err)
+	}
+	return cs
+}
+
+// function to parse all certificate files
+func (cs *certStore) load() error {
+	var certs []tls.Certificate
+	for _, file := range cs.files {
+		cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+
+	cs.mu.Lock()
+	cs.certs = certs
+	cs.mu.Unlock()
+
+	cs.changed()
+	return nil
+}
+
+// function to check if any file was modified since the last check
+func (cs *certStore) changed() bool {
+	changed := false
+	for _, file := range cs.files {
+		for _, name := range []string{file.CertFile, file.KeyFile} {
+			info, err := os.Stat(name)
+			if err != nil {
+				continue
+			}
+			if !info.ModTime().Equal(cs.modTimes[name]) {
+				cs.modTimes[name] = info.ModTime()
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// function to pick certificate as per SNI
+func (cs *certStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	if len(cs.certs) == 0 {
+		return nil, errors.New("no certificates")
+	}
+	for i := range cs.certs {
+		if hello.SupportsCertificate(&cs.certs[i]) == nil {
+			return &cs.certs[i], nil
+		}
+	}
+
+	// falling back to the first certificate
+	return &cs.certs[0], nil
+}
+
+// function to reload rotated certificate files periodically
+func (cs *certStore) watch(ctx context.Context, interval time.Duration) {
+
+	// initialize ticker to tick every interval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		// reloading only if files were modified
+		case <-ticker.C:
+			if !cs.changed() {
+				continue
+			}
+			if err := cs.load(); err != nil {
+				log.Printf("Error reloading certificates: %v", err)
+				continue
+			}
+			log.Println("Certificates reloaded")
+
+		// returning from function if context is cancelled
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// mapping of config value -> client auth type
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// mapping of config value -> tls version
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// function to initialize tls config of the listener
+func NewTLSConfig(ctx context.Context, cfg *utils.TLS) *tls.Config {
+	store := newCertStore(cfg.Certificates)
+
+	// starting the reloading of certificates as a go routine
+	go store.watch(ctx, cfg.ReloadInterval)
+
+	clientAuth, exists := clientAuthTypes[cfg.ClientAuth]
+	if !exists {
+		log.Fatalf("no such client auth %s", cfg.ClientAuth)
+	}
+	minVersion, exists := tlsVersions[cfg.MinVersion]
+	if !exists {
+		log.Fatalf("unsupported tls version %s", cfg.MinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: store.GetCertificate,
+		ClientAuth:     clientAuth,
+		MinVersion:     minVersion,
+	}
+
+	// trusting certificate authorities for client certificates
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			log.Fatalf("unable to read client CA bundle %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			log.Fatalf("no certificates found in %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	return tlsConfig
+}
@@ -0,0 +1,83 @@
+// tracing.go
+package proxy
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer of proxy spans
+var tracer = otel.Tracer("github.com/Sp92535/GoGate-RateLimiter/internal/proxy")
+
+// function to start span of an inbound request continuing trace of the caller
+func (h *Handler) startSpan(r *http.Request) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, h.name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("client.address", ClientKey(r)),
+		),
+	)
+	return r.WithContext(ctx), span
+}
+
+// function to end span of an inbound request with its outcome
+func endSpan(span trace.Span, entry *accessEntry) {
+	span.SetAttributes(
+		attribute.String("gogate.decision", entry.decision),
+		attribute.Int("http.response.status_code", entry.w.status),
+	)
+	if entry.remaining >= 0 {
+		span.SetAttributes(attribute.Int("gogate.remaining", entry.remaining))
+	}
+	if entry.w.status >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(entry.w.status))
+	}
+	span.End()
+}
+
+// transport tracing calls to destinations and propagating trace context to them
+type tracingTransport struct {
+	http.RoundTripper
+}
+
+// function to make traced call to destination
+func (t *tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// calls outside of a request such as health probes are not traced
+	if !trace.SpanContextFromContext(r.Context()).IsValid() {
+		return t.RoundTripper.RoundTrip(r)
+	}
+
+	ctx, span := tracer.Start(r.Context(), "upstream "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("server.address", r.URL.Host),
+			attribute.String("url.full", r.URL.String()),
+		),
+	)
+	defer span.End()
+
+	// request is owned by the reverse proxy so headers can be set in place
+	r = r.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := t.RoundTripper.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= 500 {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	return res, nil
+}
@@ -0,0 +1,207 @@
+// route.go
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// rank of match types, lower is tried first
+var matchTypes = map[string]int{
+	"EXACT":  0,
+	"PREFIX": 1,
+	"REGEX":  2,
+}
+
+// route blueprint
+type Route struct {
+	// name of the resource
+	Name string
+
+	// path matching
+	matchType string
+	path      string
+	regex     *regexp.Regexp
+
+	// host and header matching
+	host    string
+	headers map[string]string
+
+	// path rewriting
+	stripPrefix bool
+	rewrite     string
+
+	// precedence among overlapping routes
+	priority int
+	order    int
+
+	// handler serving matched requests
+	handler http.Handler
+}
+
+// constructor to initialize route for a resource
+func NewRoute(resource *utils.Resource, order int, handler http.Handler) (*Route, error) {
+	match := resource.Match
+	if _, exists := matchTypes[match.Type]; !exists {
+		return nil, fmt.Errorf("no such match type %s", match.Type)
+	}
+
+	route := &Route{
+		Name:        resource.Name,
+		matchType:   match.Type,
+		path:        match.Path,
+		host:        strings.ToLower(match.Host),
+		headers:     match.Headers,
+		stripPrefix: *resource.StripPrefix,
+		rewrite:     resource.Rewrite,
+		priority:    match.Priority,
+		order:       order,
+		handler:     handler,
+	}
+
+	// compiling regex once, anchored to the whole path
+	if match.Type == "REGEX" {
+		regex, err := regexp.Compile("^(?:" + match.Path + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %s: %v", match.Path, err)
+		}
+		route.regex = regex
+	}
+
+	return route, nil
+}
+
+// function to get handler serving matched requests
+func (rt *Route) Handler() http.Handler {
+	return rt.handler
+}
+
+// function to check if route should be tried before another route
+func (rt *Route) before(other *Route) bool {
+	// explicit priority first
+	if rt.priority != other.priority {
+		return rt.priority > other.priority
+	}
+	// host specific routes before generic ones
+	if (rt.host != "") != (other.host != "") {
+		return rt.host != ""
+	}
+	// exact, then prefix, then regex
+	if matchTypes[rt.matchType] != matchTypes[other.matchType] {
+		return matchTypes[rt.matchType] < matchTypes[other.matchType]
+	}
+	// longest prefix wins
+	if rt.matchType == "PREFIX" && len(rt.path) != len(other.path) {
+		return len(rt.path) > len(other.path)
+	}
+	// more header conditions are more specific
+	if len(rt.headers) != len(other.headers) {
+		return len(rt.headers) > len(other.headers)
+	}
+	// order of declaration as tie breaker
+	return rt.order < other.order
+}
+
+// function to check if request matches the route and get the rewritten path
+func (rt *Route) match(r *http.Request) (string, bool) {
+	if !rt.matchHost(r) || !rt.matchHeaders(r) {
+		return "", false
+	}
+
+	path := r.URL.Path
+	switch rt.matchType {
+
+	case "EXACT":
+		if path != rt.path {
+			return "", false
+		}
+		if rt.rewrite != "" {
+			return rt.rewrite, true
+		}
+		if rt.stripPrefix {
+			return "", true
+		}
+		return path, true
+
+	case "PREFIX":
+		rest, ok := trimSegmentPrefix(path, rt.path)
+		if !ok {
+			return "", false
+		}
+		if rt.rewrite != "" {
+			return joinPath(rt.rewrite, rest), true
+		}
+		if rt.stripPrefix {
+			return joinPath("", rest), true
+		}
+		return path, true
+
+	case "REGEX":
+		groups := rt.regex.FindStringSubmatchIndex(path)
+		if groups == nil {
+			return "", false
+		}
+		if rt.rewrite != "" {
+			return string(rt.regex.ExpandString(nil, rt.rewrite, path, groups)), true
+		}
+		return path, true
+	}
+
+	return "", false
+}
+
+// function to match host ignoring port, supports leading wildcard
+func (rt *Route) matchHost(r *http.Request) bool {
+	if rt.host == "" {
+		return true
+	}
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if suffix, ok := strings.CutPrefix(rt.host, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == rt.host
+}
+
+// function to match all required headers
+func (rt *Route) matchHeaders(r *http.Request) bool {
+	for name, value := range rt.headers {
+		values, exists := r.Header[http.CanonicalHeaderKey(name)]
+		if !exists {
+			return false
+		}
+		// "*" only requires presence of header
+		if value != "*" && values[0] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// function to trim prefix only at path segment boundaries
+// so /goo matches /goo and /goo/search but not /goober
+func trimSegmentPrefix(path string, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok {
+		return "", false
+	}
+	if rest == "" || strings.HasSuffix(prefix, "/") || strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return "", false
+}
+
+// function to join two path parts with a single slash
+func joinPath(a string, b string) string {
+	if b == "" {
+		return a
+	}
+	return strings.TrimSuffix(a, "/") + "/" + strings.TrimPrefix(b, "/")
+}
@@ -0,0 +1,70 @@
+// router.go
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// router dispatching requests to resources
+type Router struct {
+	// all routes in order of precedence
+	routes []*Route
+}
+
+// constructor to initialize router
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// function to register a new route keeping routes in order of precedence
+func (rtr *Router) Add(route *Route) {
+	rtr.routes = append(rtr.routes, route)
+	sort.SliceStable(rtr.routes, func(i, j int) bool {
+		return rtr.routes[i].before(rtr.routes[j])
+	})
+}
+
+// function to get the first route matching the request
+func (rtr *Router) Match(r *http.Request) (*Route, string) {
+	for _, route := range rtr.routes {
+		if path, ok := route.match(r); ok {
+			return route, path
+		}
+	}
+	return nil, ""
+}
+
+// function to get the route matching the request and rewrite its path for the destination
+// returns nil if no route matches
+func (rtr *Router) Resolve(r *http.Request) *Route {
+	route, path := rtr.Match(r)
+	if route == nil {
+		return nil
+	}
+
+	// rewriting the path for the destination, template may carry a query
+	if path, query, ok := strings.Cut(path, "?"); ok {
+		r.URL.Path = path
+		if r.URL.RawQuery != "" {
+			query += "&" + r.URL.RawQuery
+		}
+		r.URL.RawQuery = query
+	} else {
+		r.URL.Path = path
+	}
+	r.URL.RawPath = ""
+
+	return route
+}
+
+// function to serve request through the matched route
+func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := rtr.Resolve(r)
+	if route == nil {
+		http.NotFound(w, r)
+		return
+	}
+	route.handler.ServeHTTP(w, r)
+}
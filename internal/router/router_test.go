@@ -0,0 +1,115 @@
+// router_test.go
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+)
+
+// function to build a router of resources in order of declaration
+func newTestRouter(t *testing.T, resources []utils.Resource) *Router {
+	t.Helper()
+	rtr := NewRouter()
+	for i := range resources {
+		resource := &resources[i]
+		if resource.StripPrefix == nil {
+			strip := true
+			resource.StripPrefix = &strip
+		}
+		route, err := NewRoute(resource, i, nil)
+		if err != nil {
+			t.Fatalf("route of %s: %v", resource.Name, err)
+		}
+		rtr.Add(route)
+	}
+	return rtr
+}
+
+func TestRouterResolve(t *testing.T) {
+	keep := false
+	rtr := newTestRouter(t, []utils.Resource{
+		{Name: "goo", Match: utils.Match{Type: "PREFIX", Path: "/goo"}},
+		{Name: "goo-search", Match: utils.Match{Type: "PREFIX", Path: "/goo/search"}},
+		{Name: "health", Match: utils.Match{Type: "EXACT", Path: "/health"}, Rewrite: "/status"},
+		{Name: "users", Match: utils.Match{Type: "REGEX", Path: `/users/(\d+)`}, Rewrite: "/v2/users/$1?full=1"},
+		{Name: "api", Match: utils.Match{Type: "PREFIX", Path: "/api"}, StripPrefix: &keep},
+		{Name: "beta", Match: utils.Match{Type: "PREFIX", Path: "/api", Headers: map[string]string{"X-Beta": "*"}}},
+		{Name: "tenant", Match: utils.Match{Type: "PREFIX", Path: "/", Host: "*.example.com"}},
+		{Name: "pinned", Match: utils.Match{Type: "PREFIX", Path: "/", Priority: 10, Host: "admin.example.com"}},
+	})
+
+	tests := []struct {
+		name     string
+		host     string
+		target   string
+		headers  map[string]string
+		resource string
+		path     string
+		query    string
+	}{
+		{name: "prefix strips", target: "/goo/x", resource: "goo", path: "/x"},
+		{name: "prefix alone", target: "/goo", resource: "goo", path: ""},
+		{name: "segment boundary", target: "/goober", resource: ""},
+		{name: "longest prefix", target: "/goo/search/q", resource: "goo-search", path: "/q"},
+		{name: "exact rewrite", target: "/health", resource: "health", path: "/status"},
+		{name: "exact only", target: "/health/x", resource: ""},
+		{name: "regex groups", target: "/users/42?a=b", resource: "users", path: "/v2/users/42", query: "full=1&a=b"},
+		{name: "regex anchored", target: "/users/42/posts", resource: ""},
+		{name: "keep prefix", target: "/api/v1", resource: "api", path: "/api/v1"},
+		{name: "header present", target: "/api/v1", headers: map[string]string{"X-Beta": "1"}, resource: "beta", path: "/v1"},
+		{name: "wildcard host", host: "acme.example.com:8080", target: "/x", resource: "tenant", path: "/x"},
+		{name: "wildcard needs subdomain", host: "example.com", target: "/x", resource: ""},
+		{name: "priority first", host: "admin.example.com", target: "/goo", resource: "pinned", path: "/goo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+
+			route := rtr.Resolve(r)
+			if tt.resource == "" {
+				if route != nil {
+					t.Fatalf("got route %s, want none", route.Name)
+				}
+				return
+			}
+			if route == nil {
+				t.Fatalf("got no route, want %s", tt.resource)
+			}
+			if route.Name != tt.resource {
+				t.Fatalf("got route %s, want %s", route.Name, tt.resource)
+			}
+			if r.URL.Path != tt.path {
+				t.Errorf("got path %q, want %q", r.URL.Path, tt.path)
+			}
+			if r.URL.RawQuery != tt.query {
+				t.Errorf("got query %q, want %q", r.URL.RawQuery, tt.query)
+			}
+		})
+	}
+}
+
+func TestNewRouteInvalid(t *testing.T) {
+	strip := true
+	tests := []struct {
+		name  string
+		match utils.Match
+	}{
+		{name: "match type", match: utils.Match{Type: "GLOB", Path: "/x"}},
+		{name: "regex", match: utils.Match{Type: "REGEX", Path: "(["}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRoute(&utils.Resource{Name: "x", Match: tt.match, StripPrefix: &strip}, 0, nil); err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
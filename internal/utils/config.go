@@ -2,8 +2,12 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -11,119 +15,925 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// bytes per second of request and response bodies
+type BandwidthLimit struct {
+	Upload   string `yaml:"upload"`
+	Download string `yaml:"download"`
+	// separate bucket for every client
+	PerClient     bool `yaml:"per_client"`
+	UploadBytes   int
+	DownloadBytes int
+}
+
+// cost of requests matching a path pattern
+type PathCost struct {
+	Pattern string `yaml:"pattern"`
+	Cost    int    `yaml:"cost"`
+}
+
+// no of units consumed by a request
+type Cost struct {
+	Default int `yaml:"default"`
+	// header or query parameter carrying the cost, may only raise the configured cost
+	Header string `yaml:"header"`
+	Query  string `yaml:"query"`
+	// first matching pattern wins over default
+	Paths []PathCost `yaml:"paths"`
+	// upper bound for any cost
+	Max int `yaml:"max"`
+}
+
 // ratelimit per http request method
 type RateLimit struct {
-	Capacity     int    `yaml:"capacity"`
-	Rate         string `yaml:"rate"`
-	Strategy     string `yaml:"strategy"`
+	Capacity  int             `yaml:"capacity"`
+	Rate      string          `yaml:"rate"`
+	Strategy  string          `yaml:"strategy"`
+	Bandwidth *BandwidthLimit `yaml:"bandwidth"`
+	Cost      *Cost           `yaml:"cost"`
+	// reject (default) or delay throttled requests up to max delay
+	Mode     string        `yaml:"mode"`
+	MaxDelay time.Duration `yaml:"max_delay"`
+	// evaluate the limit without enforcing it
+	DryRun bool `yaml:"dry_run"`
+	// separate limit per ip, api_key, header:<name>, query:<name> or claim:<name>
+	Key string `yaml:"key"`
+	// most keys limited at once, least recently used ones are dropped beyond it
+	MaxKeys int `yaml:"max_keys"`
+
 	NoOfRequests int
 	TimeDuration time.Duration
+
+	// prefix of keys of the limit in redis, random if not set
+	ID string `yaml:"-"`
+}
+
+// backend instance behind a resource
+type destination struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"`
+}
+
+// periodic probing of every destination
+type ActiveHealthCheck struct {
+	Path               string        `yaml:"path"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+}
+
+// ejection of destinations failing real traffic
+type PassiveHealthCheck struct {
+	MaxFails      int           `yaml:"max_fails"`
+	EjectDuration time.Duration `yaml:"eject_duration"`
+}
+
+// health checking of destinations
+type HealthCheck struct {
+	Active  *ActiveHealthCheck  `yaml:"active"`
+	Passive *PassiveHealthCheck `yaml:"passive"`
+}
+
+// settings of connections to the destinations
+type Transport struct {
+	DialTimeout           time.Duration `yaml:"dial_timeout"`
+	KeepAlive             time.Duration `yaml:"keep_alive"`
+	TLSHandshakeTimeout   time.Duration `yaml:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout"`
+	IdleConnTimeout       time.Duration `yaml:"idle_conn_timeout"`
+	MaxIdleConns          int           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost   int           `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost       int           `yaml:"max_conns_per_host"`
+	HTTP2                 *bool         `yaml:"http2"`
+	ProxyURL              string        `yaml:"proxy_url"`
+	InsecureSkipVerify    bool          `yaml:"insecure_skip_verify"`
+	ServerName            string        `yaml:"server_name"`
+	CAFile                string        `yaml:"ca_file"`
+	CertFile              string        `yaml:"cert_file"`
+	KeyFile               string        `yaml:"key_file"`
+	// http2 with prior knowledge to plain text destinations, default for grpc resources
+	H2C *bool `yaml:"h2c"`
+}
+
+// rules to match requests to a resource
+type Match struct {
+	// EXACT, PREFIX or REGEX
+	Type string `yaml:"type"`
+	Path string `yaml:"path"`
+	// host with optional leading wildcard like *.example.com
+	Host string `yaml:"host"`
+	// required header values, "*" only requires presence
+	Headers map[string]string `yaml:"headers"`
+	// higher priority is matched first
+	Priority int `yaml:"priority"`
+}
+
+// limits of websocket, sse and grpc streams
+type Streaming struct {
+	// concurrent streams per client
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// websocket messages per session
+	MessageLimit *RateLimit `yaml:"message_limit"`
+	// bytes per second of every streaming response
+	Bandwidth      string `yaml:"bandwidth"`
+	BytesPerSecond int
+}
+
+// callers matched by ip, api key or header
+type AccessList struct {
+	// single ips or cidr ranges
+	IPs     []string `yaml:"ips"`
+	APIKeys []string `yaml:"api_keys"`
+	// required header values, "*" only requires presence
+	Headers map[string]string `yaml:"headers"`
+	// redis set of ips, cidrs or api keys changeable at runtime
+	RedisSet        string        `yaml:"redis_set"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// callers bypassing limits and callers blocked outright
+type Access struct {
+	Allow *AccessList `yaml:"allow"`
+	Deny  *AccessList `yaml:"deny"`
+	// header carrying the api key
+	APIKeyHeader string `yaml:"api_key_header"`
+}
+
+// function to set defaults of access lists
+func (a *Access) setDefaults() {
+	if a == nil {
+		return
+	}
+	if a.APIKeyHeader == "" {
+		a.APIKeyHeader = "X-API-Key"
+	}
+	for _, list := range []*AccessList{a.Allow, a.Deny} {
+		if list != nil && list.RefreshInterval <= 0 {
+			list.RefreshInterval = 10 * time.Second
+		}
+	}
+}
+
+// temporary bans of clients repeatedly exceeding limits
+type Penalty struct {
+	// no of throttles within window leading to a ban
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+	// first ban, doubled on every repeated offense till max ban
+	Ban    time.Duration `yaml:"ban"`
+	MaxBan time.Duration `yaml:"max_ban"`
+	// time for which offenses are remembered for escalation
+	Memory time.Duration `yaml:"memory"`
+}
+
+// api key with its metadata, only the sha256 hash of the key is stored
+type APIKey struct {
+	Hash    string `yaml:"hash" json:"hash"`
+	Owner   string `yaml:"owner" json:"owner"`
+	Tier    string `yaml:"tier" json:"tier"`
+	Enabled *bool  `yaml:"enabled" json:"enabled"`
+}
+
+// authentication by api key
+type APIKeyAuth struct {
+	// header or query parameter carrying the key
+	Header string   `yaml:"header"`
+	Query  string   `yaml:"query"`
+	Keys   []APIKey `yaml:"keys"`
+	// redis hash of sha256 hash -> json metadata, changeable at runtime
+	RedisHash string        `yaml:"redis_hash"`
+	CacheTTL  time.Duration `yaml:"cache_ttl"`
+}
+
+// authentication by bearer json web token
+type JWTAuth struct {
+	// HS256, RS256 and ES256
+	Algorithms []string `yaml:"algorithms"`
+	// shared secret for HS256
+	Secret string `yaml:"secret"`
+	// key set for RS256 and ES256, inline or from file reloaded when modified
+	JWKS           string        `yaml:"jwks"`
+	JWKSFile       string        `yaml:"jwks_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// optional checks of registered claims
+	Issuer   string        `yaml:"issuer"`
+	Audience string        `yaml:"audience"`
+	Leeway   time.Duration `yaml:"leeway"`
+	// claim selecting the tier of the caller
+	TierClaim string `yaml:"tier_claim"`
+	// mapping of claim -> header forwarded to destination
+	ForwardClaims map[string]string `yaml:"forward_claims"`
+}
+
+// authentication of callers
+type Auth struct {
+	APIKey *APIKeyAuth `yaml:"api_key"`
+	JWT    *JWTAuth    `yaml:"jwt"`
+}
+
+// response to throttled and banned requests
+type ThrottledResponse struct {
+	// 429 (default) or 503
+	Status      int    `yaml:"status"`
+	ContentType string `yaml:"content_type"`
+	// text/template over .Error, .Limit, .Remaining, .Reset, .RetryAfter, .Resource, .Method, .Path and .RequestID
+	// json object of the fields if not set and content type is json, {{json .Path}} quotes a field
+	Body    string            `yaml:"body"`
+	Headers map[string]string `yaml:"headers"`
 }
 
 // indivisual endpoint tracking
-type resource struct {
+type Resource struct {
 	Name           string `yaml:"name"`
 	Endpoint       string `yaml:"endpoint"`
 	DestinationURL string `yaml:"destination_url"`
-	// key = http request method
+	// http (default) or grpc, grpc resources match /package.Service/Method and are limited per grpc method
+	Protocol string `yaml:"protocol"`
+	// request matching and path rewriting
+	Match       Match  `yaml:"match"`
+	StripPrefix *bool  `yaml:"strip_prefix"`
+	Rewrite     string `yaml:"rewrite"`
+	// pool of backends and strategy to balance between them
+	Destinations []destination `yaml:"destinations"`
+	Balancer     string        `yaml:"balancer"`
+	HealthCheck  HealthCheck   `yaml:"health_check"`
+	Transport    Transport     `yaml:"transport"`
+	Streaming    *Streaming    `yaml:"streaming"`
+	// bandwidth shared by all methods
+	Bandwidth *BandwidthLimit `yaml:"bandwidth"`
+	// allow and deny lists of the resource
+	Access *Access `yaml:"access"`
+	// bans of clients repeatedly throttled
+	Penalty *Penalty `yaml:"penalty"`
+	// authentication of callers
+	Auth *Auth `yaml:"auth"`
+	// response to throttled requests, plain text 429 if not set
+	ThrottledResponse *ThrottledResponse `yaml:"throttled_response"`
+	// key = http request method, or for grpc the full method, method name or * for all methods
 	RateLimits map[string]*RateLimit `yaml:"rate_limits"`
+	// rate limits replacing the default ones for a tier of api keys, key = tier
+	Tiers map[string]map[string]*RateLimit `yaml:"tiers"`
+}
+
+// certificate and its private key
+type Certificate struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// tls termination at the listener
+type TLS struct {
+	// certificates picked as per SNI, first one is the default
+	Certificates []Certificate `yaml:"certificates"`
+	ClientCAFile string        `yaml:"client_ca_file"`
+	ClientAuth   string        `yaml:"client_auth"`
+	MinVersion   string        `yaml:"min_version"`
+	// interval to check certificate files for rotation
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// redis holding state shared by all replicas
+type Redis struct {
+	Address  string `yaml:"address"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// structured logging
+type Logging struct {
+	// debug, info, warn or error
+	Level string `yaml:"level"`
+	// json or text
+	Format string `yaml:"format"`
+	// stdout, stderr or path of a file
+	Output string `yaml:"output"`
+}
+
+// tracing exported to an opentelemetry collector
+type Tracing struct {
+	// address of the collector, tracing is disabled if not set
+	Endpoint string `yaml:"endpoint"`
+	// grpc or http
+	Protocol string `yaml:"protocol"`
+	// plain text connection to the collector
+	Insecure    bool   `yaml:"insecure"`
+	ServiceName string `yaml:"service_name"`
+	// fraction of new traces sampled, traces started upstream follow the caller
+	SampleRatio *float64 `yaml:"sample_ratio"`
+}
+
+// entry of a descriptor and limit of descriptors ending at it, as in envoy's ratelimit service
+type Descriptor struct {
+	Key string `yaml:"key"`
+	// any value matches if not set, every value gets its own limit
+	Value       string       `yaml:"value"`
+	RateLimit   *RateLimit   `yaml:"rate_limit"`
+	Descriptors []Descriptor `yaml:"descriptors"`
+}
+
+// descriptors checked under a domain
+type Domain struct {
+	Name        string       `yaml:"domain"`
+	Descriptors []Descriptor `yaml:"descriptors"`
+}
+
+// service answering rate limit checks without proxying
+type DecisionService struct {
+	Host string `yaml:"host"`
+	// envoy ratelimit.v3 grpc api, disabled if not set
+	GRPCPort string `yaml:"grpc_port"`
+	// POST /check json api, disabled if not set
+	HTTPPort string   `yaml:"http_port"`
+	Domains  []Domain `yaml:"domains"`
+}
+
+// endpoint answering auth subrequests of fronting proxies
+type ForwardAuth struct {
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+	// ips and cidrs of proxies allowed to send subrequests, any if empty
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// headers the client address is taken from, first one present wins, only read from trusted proxies
+	ClientIPHeaders []string `yaml:"client_ip_headers"`
+	// header carrying host of the original request, ignored if not set
+	HostHeader string `yaml:"host_header"`
 }
 
 type configuration struct {
 
 	// server info
 	Server struct {
+		Host  string `yaml:"host"`
+		Port  string `yaml:"port"`
+		TLS   *TLS   `yaml:"tls"`
+		HTTP2 *bool  `yaml:"http2"`
+		// header carrying id of a request, taken from the caller if present
+		RequestIDHeader string `yaml:"request_id_header"`
+	}
+
+	// admin api info, disabled if port is not set
+	Admin struct {
 		Host string `yaml:"host"`
 		Port string `yaml:"port"`
 	}
 
+	// auth subrequests of fronting proxies, disabled if port is not set
+	ForwardAuth ForwardAuth `yaml:"forward_auth"`
+
+	// allow and deny lists of all resources
+	Access *Access `yaml:"access"`
+
+	// redis holding state of all limits
+	Redis Redis `yaml:"redis"`
+
+	// access and server logs
+	Logging Logging `yaml:"logging"`
+
+	// traces of requests
+	Tracing Tracing `yaml:"tracing"`
+
+	// decisions for callers doing their own proxying
+	Decision DecisionService `yaml:"decision"`
+
 	// list of all resources
-	Resources []resource
+	Resources []Resource
+}
+
+// function to get prefix of keys of a limit, followed by client key
+// gogate:limit:<resource>:<tier>:<method>
+func LimitID(resource, tier, method string) string {
+	return "gogate:limit:" + resource + ":" + tier + ":" + method
 }
 
-// constructor to get configuration from data
+// function to get prefix of keys of a descriptor limit, apart from limits of resources
+// gogate:decision:<domain>:<path>
+func DecisionID(domain, path string) string {
+	return "gogate:decision:" + domain + ":" + path
+}
+
+// constructor to get configuration from data, exits if invalid
 func NewConfiguration(filePath string) *configuration {
+	cfg, err := LoadConfiguration(filePath)
+	if err != nil {
+		log.Fatalf("invalid config %s\n%v", filePath, err)
+	}
+	return cfg
+}
+
+// function to load configuration from data
+// every malformed setting is reported, joined in the error
+func LoadConfiguration(filePath string) (*configuration, error) {
 	var cfg configuration
 
 	// read yaml
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		log.Fatalf("unable to read config %v", err)
+		return nil, fmt.Errorf("unable to read config %v", err)
 	}
 
 	// decoding the yaml data
 	err = yaml.Unmarshal(data, &cfg)
 	if err != nil {
-		log.Fatalf("unable to load config %v", err)
+		return nil, fmt.Errorf("unable to load config %v", err)
 	}
 
-	// splitting each rate to reqs and time duration
-	for _, resource := range cfg.Resources {
-		for key, val := range resource.RateLimits {
+	// problems found while checking settings
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
 
-			reqStr := strings.Split(val.Rate, "/")
+	// validating tls settings
+	if tls := cfg.Server.TLS; tls != nil {
+		if len(tls.Certificates) == 0 {
+			invalid("no certificates for tls")
+		}
+		if tls.ReloadInterval <= 0 {
+			tls.ReloadInterval = 30 * time.Second
+		}
+	}
 
-			// setting time duration
+	if cfg.Server.RequestIDHeader == "" {
+		cfg.Server.RequestIDHeader = "X-Request-ID"
+	}
 
-			// extracting time unit
-			timeUnit := reqStr[1][len(reqStr[1])-1]
-			// extracting time value
-			temp := strings.TrimSuffix(reqStr[1], string(timeUnit))
+	// defaults for forward auth, the address appended by the fronting proxy
+	for _, proxy := range cfg.ForwardAuth.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				invalid("invalid trusted proxy %s", proxy)
+			}
+		}
+	}
+	if cfg.ForwardAuth.ClientIPHeaders == nil {
+		cfg.ForwardAuth.ClientIPHeaders = []string{"X-Forwarded-For"}
+	}
 
-			timeValue := 1
-			if temp != "" {
-				// parsing time value to integer
-				timeValue, err = strconv.Atoi(temp)
-				if err != nil {
-					log.Fatalf("error parsing %v", err)
+	if cfg.Redis.Address == "" {
+		cfg.Redis.Address = "localhost:6379"
+	}
+
+	// defaults for logging
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "json"
+	}
+	if cfg.Logging.Format != "json" && cfg.Logging.Format != "text" {
+		invalid("invalid log format %s", cfg.Logging.Format)
+	}
+	if cfg.Logging.Output == "" {
+		cfg.Logging.Output = "stdout"
+	}
+
+	// defaults for tracing
+	if cfg.Tracing.Protocol == "" {
+		cfg.Tracing.Protocol = "grpc"
+	}
+	if cfg.Tracing.Protocol != "grpc" && cfg.Tracing.Protocol != "http" {
+		invalid("invalid tracing protocol %s", cfg.Tracing.Protocol)
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = "gogate"
+	}
+	if cfg.Tracing.SampleRatio == nil {
+		ratio := 1.0
+		cfg.Tracing.SampleRatio = &ratio
+	}
+
+	cfg.Access.setDefaults()
+
+	for i := range cfg.Resources {
+		resource := &cfg.Resources[i]
+		resource.Access.setDefaults()
+
+		// defaults for api key authentication
+		if resource.Auth != nil && resource.Auth.APIKey != nil {
+			apiKey := resource.Auth.APIKey
+			if apiKey.Header == "" && apiKey.Query == "" {
+				apiKey.Header = "X-API-Key"
+			}
+			if apiKey.CacheTTL <= 0 {
+				apiKey.CacheTTL = 30 * time.Second
+			}
+		}
+
+		// defaults for jwt authentication
+		if resource.Auth != nil && resource.Auth.JWT != nil {
+			jwt := resource.Auth.JWT
+			if len(jwt.Algorithms) == 0 {
+				if jwt.Secret != "" {
+					jwt.Algorithms = append(jwt.Algorithms, "HS256")
 				}
+				if jwt.JWKS != "" || jwt.JWKSFile != "" {
+					jwt.Algorithms = append(jwt.Algorithms, "RS256", "ES256")
+				}
+			}
+			if len(jwt.Algorithms) == 0 {
+				invalid("no secret or jwks for jwt of resource %s", resource.Name)
+			}
+			for _, alg := range jwt.Algorithms {
+				if alg != "HS256" && alg != "RS256" && alg != "ES256" {
+					invalid("unsupported jwt algorithm %s", alg)
+				}
+			}
+			if jwt.ReloadInterval <= 0 {
+				jwt.ReloadInterval = time.Minute
+			}
+		}
+
+		// defaults for penalty box
+		if penalty := resource.Penalty; penalty != nil {
+			if penalty.Threshold <= 0 {
+				penalty.Threshold = 10
+			}
+			if penalty.Window <= 0 {
+				penalty.Window = time.Minute
+			}
+			if penalty.Ban <= 0 {
+				penalty.Ban = 5 * time.Minute
+			}
+			if penalty.MaxBan < penalty.Ban {
+				penalty.MaxBan = max(24*time.Hour, penalty.Ban)
+			}
+			if penalty.Memory <= 0 {
+				penalty.Memory = 24 * time.Hour
 			}
+		}
 
-			// setting time duration as per unit
-			switch timeUnit {
-			case 'h':
-				resource.RateLimits[key].TimeDuration = time.Duration(timeValue) * time.Hour
-			case 'm':
-				resource.RateLimits[key].TimeDuration = time.Duration(timeValue) * time.Minute
-			case 's':
-				resource.RateLimits[key].TimeDuration = time.Duration(timeValue) * time.Second
-			default:
-				log.Fatalf("invalid time unit: %c", timeUnit)
+		// defaults for throttled response
+		if throttled := resource.ThrottledResponse; throttled != nil {
+			if throttled.Status == 0 {
+				throttled.Status = 429
+			}
+			if throttled.Status != 429 && throttled.Status != 503 {
+				invalid("invalid throttled status %d of resource %s, must be 429 or 503", throttled.Status, resource.Name)
 			}
+			if throttled.ContentType == "" {
+				throttled.ContentType = "text/plain; charset=utf-8"
+			}
+		}
 
-			// setting reqs
-			// extracting request unit
-			reqUnit := reqStr[0][len(reqStr[0])-1]
+		// validating protocol
+		if resource.Protocol == "" {
+			resource.Protocol = "http"
+		}
+		if resource.Protocol != "http" && resource.Protocol != "grpc" {
+			invalid("invalid protocol %s of resource %s", resource.Protocol, resource.Name)
+		}
 
-			// parsing and setting directly if no unit is specified
-			if _, err := strconv.Atoi(string(reqUnit)); err == nil {
+		// endpoint is a prefix match
+		if resource.Match.Path == "" {
+			resource.Match.Path = resource.Endpoint
+		}
+		if resource.Match.Type == "" {
+			resource.Match.Type = "PREFIX"
+		}
+		if resource.Match.Path == "" && resource.Match.Host == "" {
+			invalid("no endpoint for resource %s", resource.Name)
+		}
+		if resource.StripPrefix == nil {
+			// grpc destinations need the full /package.Service/Method path
+			strip := resource.Protocol != "grpc"
+			resource.StripPrefix = &strip
+		}
 
-				reqValue, err := strconv.Atoi(reqStr[0])
-				if err != nil {
-					log.Fatalf("error parsing %v", err)
-				}
-				resource.RateLimits[key].NoOfRequests = reqValue
-				continue
+		// single destination is a pool of one
+		if resource.DestinationURL != "" {
+			resource.Destinations = append(resource.Destinations, destination{URL: resource.DestinationURL})
+		}
+		if len(resource.Destinations) == 0 {
+			invalid("no destination for resource %s", resource.Name)
+		}
+		if resource.Balancer == "" {
+			resource.Balancer = "ROUND-ROBIN"
+		}
+
+		// defaults for transport
+		if resource.Transport.DialTimeout <= 0 {
+			resource.Transport.DialTimeout = 30 * time.Second
+		}
+		if resource.Transport.KeepAlive <= 0 {
+			resource.Transport.KeepAlive = 30 * time.Second
+		}
+		if resource.Transport.H2C == nil {
+			h2c := resource.Protocol == "grpc"
+			resource.Transport.H2C = &h2c
+		}
+
+		// defaults for active health checks
+		if active := resource.HealthCheck.Active; active != nil {
+			if active.Path == "" {
+				active.Path = "/"
+			}
+			if active.Interval <= 0 {
+				active.Interval = 10 * time.Second
+			}
+			if active.Timeout <= 0 {
+				active.Timeout = 2 * time.Second
+			}
+			if active.HealthyThreshold <= 0 {
+				active.HealthyThreshold = 2
+			}
+			if active.UnhealthyThreshold <= 0 {
+				active.UnhealthyThreshold = 3
 			}
+		}
 
-			// getting req value
-			reqValue, err := strconv.Atoi(strings.TrimSuffix(reqStr[0], string(reqUnit)))
+		// defaults for passive health checks
+		if passive := resource.HealthCheck.Passive; passive != nil {
+			if passive.MaxFails <= 0 {
+				passive.MaxFails = 5
+			}
+			if passive.EjectDuration <= 0 {
+				passive.EjectDuration = 30 * time.Second
+			}
+		}
+	}
+
+	// splitting each rate to reqs and time duration
+	for _, resource := range cfg.Resources {
+		for method, val := range resource.RateLimits {
+			val.ID = LimitID(resource.Name, "-", method)
+			if err := val.Parse(); err != nil {
+				invalid("resource %s: %s: %v", resource.Name, method, err)
+			}
+		}
+		for name, tier := range resource.Tiers {
+			for method, val := range tier {
+				val.ID = LimitID(resource.Name, name, method)
+				if err := val.Parse(); err != nil {
+					invalid("resource %s: tier %s: %s: %v", resource.Name, name, method, err)
+				}
+			}
+		}
+		if err := resource.Bandwidth.parse(); err != nil {
+			invalid("resource %s: bandwidth: %v", resource.Name, err)
+		}
 
-			if err != nil {
-				log.Fatalf("error parsing %v", err)
+		// limits of long lived streams
+		if streaming := resource.Streaming; streaming != nil {
+			if msg := streaming.MessageLimit; msg != nil {
+				if err := msg.Parse(); err != nil {
+					invalid("resource %s: streaming: message limit: %v", resource.Name, err)
+				}
 			}
+			if streaming.Bandwidth != "" {
+				if streaming.BytesPerSecond, err = BytesPerSecond(streaming.Bandwidth); err != nil {
+					invalid("resource %s: streaming: %v", resource.Name, err)
+				}
+			}
+		}
+	}
+
+	// limits of descriptors of the decision service
+	domains := make(map[string]bool)
+	for i := range cfg.Decision.Domains {
+		domain := &cfg.Decision.Domains[i]
+		if domain.Name == "" {
+			invalid("no name for domain of decision service")
+		}
+		if domains[domain.Name] {
+			invalid("duplicate domain %s", domain.Name)
+		}
+		domains[domain.Name] = true
+		errs = append(errs, parseDescriptors(domain.Name, "", domain.Descriptors)...)
+	}
+
+	return &cfg, errors.Join(errs...)
+}
 
-			// setting req as per unit
-			switch reqUnit {
-			case 'M':
-				resource.RateLimits[key].NoOfRequests = reqValue * 1000000
-			case 'K':
-				resource.RateLimits[key].NoOfRequests = reqValue * 1000
-			default:
-				log.Fatalf("error parsing %c not allowed", reqUnit)
+// function to parse limits of descriptors and their nested descriptors
+// path of a descriptor is key or key=value of every entry leading to it joined by /
+func parseDescriptors(domain string, path string, descriptors []Descriptor) []error {
+	var errs []error
+	for i := range descriptors {
+		descriptor := &descriptors[i]
+		if descriptor.Key == "" {
+			errs = append(errs, fmt.Errorf("no key for descriptor of domain %s", domain))
+		}
+		entry := descriptor.Key
+		if descriptor.Value != "" {
+			entry += "=" + descriptor.Value
+		}
+		if path != "" {
+			entry = path + "/" + entry
+		}
+		if descriptor.RateLimit != nil {
+			descriptor.RateLimit.ID = DecisionID(domain, entry)
+			if err := descriptor.RateLimit.Parse(); err != nil {
+				errs = append(errs, fmt.Errorf("domain %s: %s: %v", domain, entry, err))
 			}
 		}
+		errs = append(errs, parseDescriptors(domain, entry, descriptor.Descriptors)...)
 	}
+	return errs
+}
 
-	return &cfg
+// function to parse rate and validate options of a rate limit
+func (rl *RateLimit) Parse() error {
+	var err error
+	if rl.NoOfRequests, rl.TimeDuration, err = SplitRate(rl.Rate); err != nil {
+		return err
+	}
+	if err := rl.Bandwidth.parse(); err != nil {
+		return err
+	}
+
+	// buckets holding nothing never admit a request
+	if (rl.Strategy == "TOKEN-BUCKET" || rl.Strategy == "LEAKY-BUCKET") && rl.Capacity <= 0 {
+		return fmt.Errorf("capacity of %s must be positive", rl.Strategy)
+	}
+	if err := rl.Cost.validate(rl.largestCost()); err != nil {
+		return err
+	}
+
+	// validating throttling mode
+	switch rl.Mode {
+	case "":
+		rl.Mode = "reject"
+	case "reject":
+	case "delay":
+		if rl.Strategy == "LEAKY-BUCKET" {
+			return fmt.Errorf("delay mode not supported for %s, it already queues requests", rl.Strategy)
+		}
+		if rl.MaxDelay <= 0 {
+			rl.MaxDelay = time.Second
+		}
+	default:
+		return fmt.Errorf("invalid mode: %s", rl.Mode)
+	}
+
+	// validating source of partition key
+	source, _, _ := strings.Cut(rl.Key, ":")
+	if !KeySources[source] {
+		return fmt.Errorf("invalid key: %s", rl.Key)
+	}
+	if rl.MaxKeys <= 0 {
+		rl.MaxKeys = DefaultMaxKeys
+	}
+	return nil
+}
+
+// function to get largest cost a single request of the limit can ever be admitted with
+func (rl *RateLimit) largestCost() int {
+	if rl.Strategy == "TOKEN-BUCKET" || rl.Strategy == "LEAKY-BUCKET" {
+		return rl.Capacity
+	}
+	return rl.NoOfRequests
+}
+
+// function to check costs of a rate limit are neither negative nor more than the limit admits
+func (c *Cost) validate(largest int) error {
+	if c == nil {
+		return nil
+	}
+	if c.Default < 0 || c.Max < 0 {
+		return fmt.Errorf("negative cost")
+	}
+	if c.Default > largest || c.Max > largest {
+		return fmt.Errorf("cost more than %d is never admitted", largest)
+	}
+	for _, rule := range c.Paths {
+		if rule.Cost < 0 {
+			return fmt.Errorf("negative cost of path %s", rule.Pattern)
+		}
+		if rule.Cost > largest {
+			return fmt.Errorf("cost of path %s more than %d is never admitted", rule.Pattern, largest)
+		}
+
+		// validating patterns once so matching never fails
+		if _, err := path.Match(rule.Pattern, "/"); err != nil {
+			return fmt.Errorf("invalid path pattern %s: %v", rule.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// most keys of a limit kept at once if not configured
+const DefaultMaxKeys = 10000
+
+// sources a limit can be partitioned by, empty shares the limit by all clients
+var KeySources = map[string]bool{
+	"":        true,
+	"ip":      true,
+	"api_key": true,
+	"header":  true,
+	"query":   true,
+	"claim":   true,
+}
+
+// function to split rate like 10K/5s to no of units and time duration, both positive
+func SplitRate(rate string) (int, time.Duration, error) {
+	reqs, timeDuration, err := splitRate(rate)
+	if err != nil {
+		return 0, 0, err
+	}
+	if reqs <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate: %s, no of units must be positive", rate)
+	}
+	return reqs, timeDuration, nil
+}
+
+// function to split rate like 10K/5s to no of units and time duration
+func splitRate(rate string) (int, time.Duration, error) {
+	var timeDuration time.Duration
+	var err error
+
+	reqStr := strings.Split(rate, "/")
+	if len(reqStr) != 2 || reqStr[0] == "" || reqStr[1] == "" {
+		return 0, 0, fmt.Errorf("invalid rate: %s", rate)
+	}
+
+	// setting time duration
+
+	// extracting time unit
+	timeUnit := reqStr[1][len(reqStr[1])-1]
+	// extracting time value
+	temp := strings.TrimSuffix(reqStr[1], string(timeUnit))
+
+	timeValue := 1
+	if temp != "" {
+		// parsing time value to integer
+		timeValue, err = strconv.Atoi(temp)
+		if err != nil {
+			return 0, 0, fmt.Errorf("error parsing %v", err)
+		}
+	}
+
+	// setting time duration as per unit
+	switch timeUnit {
+	case 'h':
+		timeDuration = time.Duration(timeValue) * time.Hour
+	case 'm':
+		timeDuration = time.Duration(timeValue) * time.Minute
+	case 's':
+		timeDuration = time.Duration(timeValue) * time.Second
+	default:
+		return 0, 0, fmt.Errorf("invalid time unit: %c", timeUnit)
+	}
+	if timeDuration <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate: %s, duration must be positive", rate)
+	}
+
+	// setting reqs
+	// extracting request unit
+	reqUnit := reqStr[0][len(reqStr[0])-1]
+
+	// parsing and setting directly if no unit is specified
+	if _, err := strconv.Atoi(string(reqUnit)); err == nil {
+
+		reqValue, err := strconv.Atoi(reqStr[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("error parsing %v", err)
+		}
+		return reqValue, timeDuration, nil
+	}
+
+	// getting req value
+	reqValue, err := strconv.Atoi(strings.TrimSuffix(reqStr[0], string(reqUnit)))
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("error parsing %v", err)
+	}
+
+	// setting req as per unit
+	switch reqUnit {
+	case 'M':
+		return reqValue * 1000000, timeDuration, nil
+	case 'K':
+		return reqValue * 1000, timeDuration, nil
+	default:
+		return 0, 0, fmt.Errorf("error parsing %c not allowed", reqUnit)
+	}
+}
+
+// function to convert bandwidth rates to bytes per second
+func (bl *BandwidthLimit) parse() error {
+	if bl == nil {
+		return nil
+	}
+	var err error
+	if bl.Upload != "" {
+		if bl.UploadBytes, err = BytesPerSecond(bl.Upload); err != nil {
+			return err
+		}
+	}
+	if bl.Download != "" {
+		if bl.DownloadBytes, err = BytesPerSecond(bl.Download); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// function to convert rate like 1M/s to bytes per second
+func BytesPerSecond(rate string) (int, error) {
+	bytes, duration, err := SplitRate(rate)
+	if err != nil {
+		return 0, err
+	}
+	return max(1, int(float64(bytes)/duration.Seconds())), nil
 }
@@ -0,0 +1,181 @@
+// config_test.go
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// function to write a config file removed at end of the test
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	path := writeConfig(t, `
+resources:
+  - name: api
+    endpoint: /api
+    destination_url: http://localhost:8081
+    rate_limits:
+      GET:
+        strategy: TOKEN-BUCKET
+        rate: 10K/5s
+        capacity: 20
+`)
+	cfg, err := LoadConfiguration(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resource := cfg.Resources[0]
+	if resource.Match.Type != "PREFIX" || resource.Match.Path != "/api" || len(resource.Destinations) != 1 {
+		t.Errorf("got match %+v and %d destinations", resource.Match, len(resource.Destinations))
+	}
+	rateLimit := resource.RateLimits["GET"]
+	if rateLimit.NoOfRequests != 10000 || rateLimit.TimeDuration != 5*time.Second {
+		t.Errorf("got %d per %s", rateLimit.NoOfRequests, rateLimit.TimeDuration)
+	}
+	if rateLimit.ID != LimitID("api", "-", "GET") || rateLimit.Mode != "reject" || rateLimit.MaxKeys != DefaultMaxKeys {
+		t.Errorf("got id %s, mode %s and max keys %d", rateLimit.ID, rateLimit.Mode, rateLimit.MaxKeys)
+	}
+}
+
+func TestLoadConfigurationProblems(t *testing.T) {
+	path := writeConfig(t, `
+logging:
+  format: xml
+resources:
+  - name: a
+    endpoint: /a
+    destination_url: http://localhost:8081
+    rate_limits:
+      GET:
+        strategy: FIXED-WINDOW
+        rate: 10/x
+      POST:
+        strategy: LEAKY-BUCKET
+        rate: 5/s
+        capacity: 5
+        mode: delay
+      PUT:
+        strategy: TOKEN-BUCKET
+        rate: 5/s
+      DELETE:
+        strategy: FIXED-WINDOW
+        rate: 0/s
+      PATCH:
+        strategy: FIXED-WINDOW
+        rate: 5/s
+        cost:
+          default: -5
+      OPTIONS:
+        strategy: TOKEN-BUCKET
+        rate: 5/s
+        capacity: 10
+        cost:
+          max: 20
+      HEAD:
+        strategy: FIXED-WINDOW
+        rate: 100/s
+        cost:
+          paths:
+            - pattern: "/export/["
+              cost: 5
+    streaming:
+      message_limit:
+        strategy: LEAKY-BUCKET
+        rate: 10/0s
+        capacity: 5
+  - name: b
+    endpoint: /b
+decision:
+  domains:
+    - domain: d
+      descriptors:
+        - key: path
+          rate_limit: {strategy: FIXED-WINDOW, rate: 1/s, key: "cookie:a"}
+`)
+	_, err := LoadConfiguration(path)
+	if err == nil {
+		t.Fatal("got no error")
+	}
+
+	// every problem is reported, not only the first one
+	for _, want := range []string{
+		"invalid log format xml",
+		"resource a: GET: invalid time unit: x",
+		"resource a: POST: delay mode not supported",
+		"resource a: PUT: capacity of TOKEN-BUCKET must be positive",
+		"resource a: DELETE: invalid rate: 0/s",
+		"resource a: PATCH: negative cost",
+		"resource a: OPTIONS: cost more than 10 is never admitted",
+		"resource a: HEAD: invalid path pattern /export/[",
+		"resource a: streaming: message limit: invalid rate: 10/0s",
+		"no destination for resource b",
+		"domain d: path: invalid key: cookie:a",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing problem %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfigurationUnreadable(t *testing.T) {
+	cfg, err := LoadConfiguration(filepath.Join(t.TempDir(), "missing.yaml"))
+	if cfg != nil || err == nil {
+		t.Fatalf("got config %v and error %v", cfg, err)
+	}
+	cfg, err = LoadConfiguration(writeConfig(t, "resources: {"))
+	if cfg != nil || err == nil {
+		t.Fatalf("got config %v and error %v", cfg, err)
+	}
+}
+
+func TestSplitRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		reqs     int
+		duration time.Duration
+		invalid  bool
+	}{
+		{rate: "10/s", reqs: 10, duration: time.Second},
+		{rate: "5/30s", reqs: 5, duration: 30 * time.Second},
+		{rate: "2K/m", reqs: 2000, duration: time.Minute},
+		{rate: "1M/2h", reqs: 1000000, duration: 2 * time.Hour},
+		{rate: "10", invalid: true},
+		{rate: "10/", invalid: true},
+		{rate: "/s", invalid: true},
+		{rate: "10/d", invalid: true},
+		{rate: "10G/s", invalid: true},
+		{rate: "ten/s", invalid: true},
+		{rate: "10/0s", invalid: true},
+		{rate: "0/s", invalid: true},
+		{rate: "0K/m", invalid: true},
+		{rate: "-5/s", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			reqs, duration, err := SplitRate(tt.rate)
+			if tt.invalid {
+				if err == nil {
+					t.Fatalf("got %d per %s, want error", reqs, duration)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reqs != tt.reqs || duration != tt.duration {
+				t.Errorf("got %d per %s, want %d per %s", reqs, duration, tt.reqs, tt.duration)
+			}
+		})
+	}
+}
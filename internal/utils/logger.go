@@ -0,0 +1,80 @@
+// logger.go
+package utils
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// function to initialize structured logger, also used by the standard logger
+func InitLogger(cfg *Logging) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		log.Fatalf("invalid log level %s", cfg.Level)
+	}
+
+	// opening the output destination
+	var out io.Writer
+	switch cfg.Output {
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("unable to open log file %v", err)
+		}
+		out = file
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if cfg.Format == "text" {
+		handler = slog.NewTextHandler(out, opts)
+	} else {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+
+	// routing log.Printf calls through the same handler
+	logger := slog.New(&contextHandler{handler})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// key of request id in context
+type requestIDKey struct{}
+
+// function to attach request id to context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// function to get request id from context, empty if not set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// handler adding request id of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
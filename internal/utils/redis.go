@@ -2,25 +2,16 @@
 package utils
 
 import (
-	"log"
-	"os"
-
 	"github.com/redis/go-redis/v9"
 )
 
 // function to initialize redis client to interact with redis
-func InitRedis() *redis.Client {
+func InitRedis(cfg *Redis) *redis.Client {
 	rdb := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
+		Addr:     cfg.Address,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
 	})
 	return rdb
 }
-
-// function to load lua scripts as a redis script
-func LoadScript(filename string) *redis.Script {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		log.Fatalf("error loading lua scripts %v", err)
-	}
-	return redis.NewScript(string(data))
-}
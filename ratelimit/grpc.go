@@ -0,0 +1,74 @@
+// grpc.go
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// function to get key of the limit a call counts against from its context and full method
+type GRPCKeyFunc func(ctx context.Context, fullMethod string) string
+
+// key of calls by ip of the peer
+func ByPeer(ctx context.Context, fullMethod string) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return hostOf(p.Addr.String())
+}
+
+// key of calls by method, each method has its own limit
+func ByMethod(ctx context.Context, fullMethod string) string {
+	return fullMethod
+}
+
+// function to limit unary calls, throttled calls fail with RESOURCE_EXHAUSTED
+func UnaryServerInterceptor(l *Limiter, key GRPCKeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allowCall(ctx, l, key, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// function to limit streams when opened, throttled streams fail with RESOURCE_EXHAUSTED
+func StreamServerInterceptor(l *Limiter, key GRPCKeyFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allowCall(ss.Context(), l, key, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// function to decide on a call, reporting quota in header metadata
+func allowCall(ctx context.Context, l *Limiter, key GRPCKeyFunc, fullMethod string) error {
+	k := ""
+	if key != nil {
+		k = key(ctx, fullMethod)
+	}
+	decision := l.Allow(ctx, k, 1)
+
+	md := metadata.Pairs("x-ratelimit-limit", strconv.Itoa(decision.Limit))
+	if decision.Remaining >= 0 {
+		md.Append("x-ratelimit-remaining", strconv.Itoa(decision.Remaining))
+	}
+	if !decision.Allowed {
+		md.Append("retry-after", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+	}
+	grpc.SetHeader(ctx, md)
+
+	if !decision.Allowed {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return nil
+}
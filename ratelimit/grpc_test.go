@@ -0,0 +1,141 @@
+// grpc_test.go
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// transport stream of a call recording the header metadata set by interceptors
+type headerStream struct {
+	method string
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return s.method }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+// server stream of a call with a context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+// function to get context of a call from a peer, recording header metadata
+func callContext(method string, addr string) (context.Context, *headerStream) {
+	hs := &headerStream{method: method}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), hs)
+	ip, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(ctx, &peer.Peer{Addr: ip}), hs
+}
+
+func TestServerInterceptors(t *testing.T) {
+	type call struct {
+		method string
+		peer   string
+
+		// expected outcome
+		code       codes.Code
+		remaining  string
+		retryAfter string
+	}
+	tests := []struct {
+		name  string
+		key   GRPCKeyFunc
+		calls []call
+	}{
+		{
+			name: "shared limit",
+			calls: []call{
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:5000", code: codes.OK, remaining: "1"},
+				{method: "/pkg.Svc/B", peer: "10.0.0.2:5000", code: codes.OK, remaining: "0"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.3:5000", code: codes.ResourceExhausted, remaining: "0", retryAfter: "60"},
+			},
+		},
+		{
+			name: "limit per peer",
+			key:  ByPeer,
+			calls: []call{
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:5000", code: codes.OK, remaining: "1"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:6000", code: codes.OK, remaining: "0"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:7000", code: codes.ResourceExhausted, remaining: "0", retryAfter: "60"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.2:5000", code: codes.OK, remaining: "1"},
+			},
+		},
+		{
+			name: "limit per method",
+			key:  ByMethod,
+			calls: []call{
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:5000", code: codes.OK, remaining: "1"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:5000", code: codes.OK, remaining: "0"},
+				{method: "/pkg.Svc/A", peer: "10.0.0.1:5000", code: codes.ResourceExhausted, remaining: "0", retryAfter: "60"},
+				{method: "/pkg.Svc/B", peer: "10.0.0.1:5000", code: codes.OK, remaining: "1"},
+			},
+		},
+	}
+
+	// function to check outcome of a call
+	check := func(t *testing.T, i int, c call, err error, header metadata.MD, handled bool) {
+		t.Helper()
+		if got := status.Code(err); got != c.code {
+			t.Errorf("call %d: got code %s, want %s", i+1, got, c.code)
+		}
+		if handled != (c.code == codes.OK) {
+			t.Errorf("call %d: handler called %t", i+1, handled)
+		}
+		if got := header.Get("x-ratelimit-limit"); len(got) != 1 || got[0] != "2" {
+			t.Errorf("call %d: got limit %q, want 2", i+1, got)
+		}
+		if got := header.Get("x-ratelimit-remaining"); len(got) != 1 || got[0] != c.remaining {
+			t.Errorf("call %d: got remaining %q, want %q", i+1, got, c.remaining)
+		}
+		got := header.Get("retry-after")
+		if c.retryAfter == "" && len(got) > 0 || c.retryAfter != "" && (len(got) != 1 || got[0] != c.retryAfter) {
+			t.Errorf("call %d: got retry after %q, want %q", i+1, got, c.retryAfter)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" unary", func(t *testing.T) {
+			interceptor := UnaryServerInterceptor(newTestLimiter(t), tt.key)
+			for i, c := range tt.calls {
+				ctx, hs := callContext(c.method, c.peer)
+				handled := false
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: c.method}, func(ctx context.Context, req any) (any, error) {
+					handled = true
+					return nil, nil
+				})
+				check(t, i, c, err, hs.header, handled)
+			}
+		})
+		t.Run(tt.name+" stream", func(t *testing.T) {
+			interceptor := StreamServerInterceptor(newTestLimiter(t), tt.key)
+			for i, c := range tt.calls {
+				ctx, hs := callContext(c.method, c.peer)
+				handled := false
+				err := interceptor(nil, &contextStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: c.method}, func(srv any, ss grpc.ServerStream) error {
+					handled = true
+					return nil
+				})
+				check(t, i, c, err, hs.header, handled)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// middleware.go
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// function to get key of the limit a request counts against
+type KeyFunc func(r *http.Request) string
+
+// function to get cost of a request
+type CostFunc func(r *http.Request) int
+
+// key of requests by ip of the client
+func ByIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+// function to get host of an address
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// function to get key of requests by a header
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// middleware options
+type MiddlewareOption func(*middleware)
+
+// middleware limiting requests before they reach the handler
+type middleware struct {
+	limiter *Limiter
+	key     KeyFunc
+	cost    CostFunc
+
+	// handler of throttled requests
+	throttled http.Handler
+}
+
+// function to set key of requests, all requests share the limit by default
+func WithKey(key KeyFunc) MiddlewareOption {
+	return func(m *middleware) {
+		m.key = key
+	}
+}
+
+// function to set cost of requests, every request costs one unit by default
+func WithCost(cost CostFunc) MiddlewareOption {
+	return func(m *middleware) {
+		m.cost = cost
+	}
+}
+
+// function to set handler of throttled requests, plain 429 by default
+func WithThrottledHandler(h http.Handler) MiddlewareOption {
+	return func(m *middleware) {
+		m.throttled = h
+	}
+}
+
+// function to wrap a handler with the limiter
+func Middleware(l *Limiter, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{
+		limiter: l,
+		key:     func(*http.Request) string { return "" },
+		cost:    func(*http.Request) int { return 1 },
+		throttled: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+		}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			decision := m.limiter.Allow(r.Context(), m.key(r), m.cost(r))
+
+			// client gave up while its request was held, nothing to answer
+			if r.Context().Err() != nil {
+				return
+			}
+			SetHeaders(w.Header(), decision)
+			if !decision.Allowed {
+				m.throttled.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// function to set rate limit headers of a decision
+func SetHeaders(header http.Header, d Decision) {
+	header.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+	if d.Remaining >= 0 {
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	}
+	if !d.Allowed && d.RetryAfter > 0 {
+		header.Set("Retry-After", strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+	}
+}
@@ -0,0 +1,121 @@
+// middleware_test.go
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// function to get a redis client of an in-memory redis
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// function to get a fixed window allowing 2 requests a minute on an in-memory redis
+func newTestLimiter(t *testing.T) *Limiter {
+	t.Helper()
+	l, err := NewFixedWindow("2/m", WithRedis(newTestRedis(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(l.Stop)
+	return l
+}
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		client string
+		cost   string
+
+		// expected response
+		status     int
+		remaining  string
+		retryAfter string
+	}
+	tests := []struct {
+		name     string
+		opts     []MiddlewareOption
+		requests []request
+	}{
+		{
+			name: "shared limit",
+			requests: []request{
+				{client: "10.0.0.1", status: http.StatusOK, remaining: "1"},
+				{client: "10.0.0.2", status: http.StatusOK, remaining: "0"},
+				{client: "10.0.0.3", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "60"},
+			},
+		},
+		{
+			name: "limit per ip",
+			opts: []MiddlewareOption{WithKey(ByIP)},
+			requests: []request{
+				{client: "10.0.0.1", status: http.StatusOK, remaining: "1"},
+				{client: "10.0.0.1", status: http.StatusOK, remaining: "0"},
+				{client: "10.0.0.1", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "60"},
+				{client: "10.0.0.2", status: http.StatusOK, remaining: "1"},
+			},
+		},
+		{
+			name: "cost",
+			opts: []MiddlewareOption{WithCost(func(r *http.Request) int {
+				cost, _ := strconv.Atoi(r.Header.Get("X-Cost"))
+				return cost
+			})},
+			requests: []request{
+				{client: "10.0.0.1", cost: "3", status: http.StatusTooManyRequests, remaining: "2", retryAfter: "60"},
+				{client: "10.0.0.1", cost: "2", status: http.StatusOK, remaining: "0"},
+			},
+		},
+		{
+			name: "throttled handler",
+			opts: []MiddlewareOption{WithThrottledHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))},
+			requests: []request{
+				{client: "10.0.0.1", status: http.StatusOK, remaining: "1"},
+				{client: "10.0.0.1", status: http.StatusOK, remaining: "0"},
+				{client: "10.0.0.1", status: http.StatusServiceUnavailable, remaining: "0", retryAfter: "60"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			h := Middleware(newTestLimiter(t), tt.opts...)(next)
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest("GET", "/", nil)
+				r.RemoteAddr = req.client + ":1234"
+				if req.cost != "" {
+					r.Header.Set("X-Cost", req.cost)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Code != req.status {
+					t.Errorf("request %d: got status %d, want %d", i+1, w.Code, req.status)
+				}
+				if got := w.Header().Get("X-RateLimit-Limit"); got != "2" {
+					t.Errorf("request %d: got limit %q, want 2", i+1, got)
+				}
+				if got := w.Header().Get("X-RateLimit-Remaining"); got != req.remaining {
+					t.Errorf("request %d: got remaining %q, want %q", i+1, got, req.remaining)
+				}
+				if got := w.Header().Get("Retry-After"); got != req.retryAfter {
+					t.Errorf("request %d: got retry after %q, want %q", i+1, got, req.retryAfter)
+				}
+			}
+		})
+	}
+}
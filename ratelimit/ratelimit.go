@@ -0,0 +1,220 @@
+// ratelimit.go
+
+// Package ratelimit runs the strategies of GoGate in process, as http middleware
+// or grpc interceptors, sharing state through redis with the gateway and other
+// processes using limits of the same name.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sp92535/GoGate-RateLimiter/internal/limiter"
+	"github.com/Sp92535/GoGate-RateLimiter/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// shortest time an unused client key is kept
+const minPartitionIdle = 5 * time.Minute
+
+// redis client of limiters created without WithRedis, set by Setup
+var defaultRdb atomic.Pointer[redis.Client]
+
+// function to preload scripts into a redis client and use it for limiters created without WithRedis
+func Setup(ctx context.Context, rdb *redis.Client) error {
+	if err := limiter.Setup(ctx, rdb); err != nil {
+		return err
+	}
+	defaultRdb.Store(rdb)
+	return nil
+}
+
+// outcome of a request
+type Decision struct {
+	// request may proceed
+	Allowed bool
+
+	// units the limit allows, capacity of buckets or requests per window
+	Limit int
+
+	// units left after the decision, -1 if unknown
+	Remaining int
+
+	// upper bound of the time till units are available again, 0 if allowed
+	RetryAfter time.Duration
+
+	// time spent queued or delayed before the decision
+	Waited time.Duration
+}
+
+// settings of a limiter
+type options struct {
+	rateLimit utils.RateLimit
+
+	// redis client holding state of the limit
+	rdb *redis.Client
+}
+
+// option of a limiter
+type Option func(*options)
+
+// function to set name of the limit in redis, limiters with the same name share state
+// name of a limit of the gateway is gogate:limit:<resource>:<tier>:<method>, random if not set
+func WithName(name string) Option {
+	return func(o *options) {
+		o.rateLimit.ID = name
+	}
+}
+
+// function to hold throttled requests up to max delay instead of rejecting them
+// not supported by leaky bucket which always queues
+func WithDelay(maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.rateLimit.Mode = "delay"
+		o.rateLimit.MaxDelay = maxDelay
+	}
+}
+
+// function to set most keys limited at once, least recently used ones are dropped beyond it
+func WithMaxKeys(maxKeys int) Option {
+	return func(o *options) {
+		o.rateLimit.MaxKeys = maxKeys
+	}
+}
+
+// function to keep state of the limit in a redis client other than the one of Setup
+// scripts are sent to the client on first use
+func WithRedis(rdb *redis.Client) Option {
+	return func(o *options) {
+		o.rdb = rdb
+	}
+}
+
+// limiter keeping a separate limit per key
+type Limiter struct {
+	rateLimit utils.RateLimit
+
+	// limiter per key
+	partitions *limiter.Partitioned
+}
+
+// constructor to initialize token bucket holding capacity tokens refilled at rate
+func NewTokenBucket(capacity int, rate string, opts ...Option) (*Limiter, error) {
+	return newLimiter("TOKEN-BUCKET", capacity, rate, opts)
+}
+
+// constructor to initialize leaky bucket queueing up to capacity requests served at rate
+func NewLeakyBucket(capacity int, rate string, opts ...Option) (*Limiter, error) {
+	return newLimiter("LEAKY-BUCKET", capacity, rate, opts)
+}
+
+// constructor to initialize fixed window allowing rate requests per window
+func NewFixedWindow(rate string, opts ...Option) (*Limiter, error) {
+	return newLimiter("FIXED-WINDOW", 0, rate, opts)
+}
+
+// constructor to initialize sliding window allowing rate requests per window
+func NewSlidingWindow(rate string, opts ...Option) (*Limiter, error) {
+	return newLimiter("SLIDING-WINDOW", 0, rate, opts)
+}
+
+// constructor to initialize sliding window log allowing rate requests per window
+func NewSlidingWindowLog(rate string, opts ...Option) (*Limiter, error) {
+	return newLimiter("SLIDING-WINDOW-LOG", 0, rate, opts)
+}
+
+// constructor to initialize limiter of a strategy
+func newLimiter(strategy string, capacity int, rate string, opts []Option) (*Limiter, error) {
+	noOfRequests, timeDuration, err := utils.SplitRate(rate)
+	if err != nil {
+		return nil, err
+	}
+	if (strategy == "TOKEN-BUCKET" || strategy == "LEAKY-BUCKET") && capacity <= 0 {
+		return nil, fmt.Errorf("capacity of %s must be positive", strategy)
+	}
+
+	o := options{
+		rateLimit: utils.RateLimit{
+			Strategy:     strategy,
+			Capacity:     capacity,
+			Rate:         rate,
+			Mode:         "reject",
+			NoOfRequests: noOfRequests,
+			TimeDuration: timeDuration,
+			MaxKeys:      utils.DefaultMaxKeys,
+		},
+		rdb: defaultRdb.Load(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.rdb == nil {
+		return nil, fmt.Errorf("redis is not set up, call Setup first or pass WithRedis")
+	}
+	if o.rateLimit.Mode == "delay" {
+		if strategy == "LEAKY-BUCKET" {
+			return nil, fmt.Errorf("delay is not supported for %s, it already queues requests", strategy)
+		}
+		if o.rateLimit.MaxDelay <= 0 {
+			return nil, fmt.Errorf("invalid max delay %s", o.rateLimit.MaxDelay)
+		}
+	}
+	if o.rateLimit.ID == "" {
+		o.rateLimit.ID = "gogate:lib:" + uuid.NewString()
+	}
+
+	l := &Limiter{rateLimit: o.rateLimit}
+	algo := limiter.Limiters[strategy]
+	l.partitions = limiter.NewPartitioned(func(key string) limiter.Limiter {
+		limit := l.rateLimit
+		limit.ID += ":" + key
+		lim := algo(o.rdb, &limit)
+		if limit.Mode == "delay" {
+			return limiter.NewDelayed(lim, &limit)
+		}
+		return lim
+	}, max(minPartitionIdle, 2*timeDuration), o.rateLimit.MaxKeys)
+
+	return l, nil
+}
+
+// function to decide on a request of a key consuming cost units
+// queued and delayed requests block till admitted, rejected or ctx is done
+func (l *Limiter) Allow(ctx context.Context, key string, cost int) Decision {
+	if key == "" {
+		key = "-"
+	}
+	start := time.Now()
+
+	req := limiter.NewRequest(uuid.NewString())
+	req.Cost = max(cost, 1)
+
+	admission := l.partitions.For(key).Admit(ctx, req)
+	decision := Decision{Limit: l.limit(), Allowed: admission.Allowed, Remaining: admission.Remaining}
+
+	// waiting till admitted by queueing strategies
+	if admission.Ticket != nil && admission.Ticket.Wait(ctx) != nil {
+		decision.Allowed = false
+	}
+	if !decision.Allowed {
+		decision.RetryAfter = l.rateLimit.TimeDuration
+	}
+	decision.Waited = time.Since(start)
+	return decision
+}
+
+// function to get no of units the limit allows
+func (l *Limiter) limit() int {
+	if l.rateLimit.Strategy == "TOKEN-BUCKET" || l.rateLimit.Strategy == "LEAKY-BUCKET" {
+		return l.rateLimit.Capacity
+	}
+	return l.rateLimit.NoOfRequests
+}
+
+// function to stop all background tasks of the limiter
+func (l *Limiter) Stop() {
+	l.partitions.Stop()
+}